@@ -2,12 +2,25 @@ package main
 
 import (
 	"context"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/auth"
+	"digiauth/pkg/main-app/config"
+	"digiauth/pkg/main-app/connections"
 	"digiauth/pkg/main-app/db"
+	"digiauth/pkg/main-app/db/migrations"
+	"digiauth/pkg/main-app/emails"
+	"digiauth/pkg/main-app/events"
+	"digiauth/pkg/main-app/exchanges"
 	issuer "digiauth/pkg/main-app/issuer/routes"
+	"digiauth/pkg/main-app/notify"
+	"digiauth/pkg/main-app/revocations"
+	"digiauth/pkg/main-app/server"
 	receiver "digiauth/pkg/main-app/user/routes"
 	verifier "digiauth/pkg/main-app/verifier/routes"
+	"fmt"
 
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -25,16 +38,72 @@ type Server struct {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			log.Fatalf("Migration failed: %v", err)
+		}
+		return
+	}
+
 	if err := run(); err != nil {
 		log.Fatalf("Application failed: %v", err)
 	}
 }
 
 func run() error {
-	if err := db.InitDB(); err != nil {
+	cfg, err := config.Load(configPath())
+	if err != nil {
 		return err
 	}
-	defer db.CloseDB()
+
+	store, err := db.NewStore(context.Background(), cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	if cfg.Database.MigrateOnStartup {
+		migrator, err := migrations.New(store.Pool())
+		if err != nil {
+			return err
+		}
+		applied, err := migrator.Up(context.Background())
+		if err != nil {
+			return err
+		}
+		for _, m := range applied {
+			log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+		}
+	}
+
+	tokens := auth.NewTokenManager(cfg.Auth)
+
+	notifier, err := notify.New(cfg.Notifier)
+	if err != nil {
+		return err
+	}
+	renderer, err := emails.New(cfg.Emails)
+	if err != nil {
+		return err
+	}
+	sealer, err := exchanges.NewSealer(cfg.CredentialExchanges.EncryptionKey)
+	if err != nil {
+		return err
+	}
+	deps := func(role, agentURL string) server.Dependencies {
+		return server.Dependencies{
+			Role:     role,
+			Config:   cfg,
+			Agent:    acapy.NewClient(agentURL, nil),
+			Store:    store,
+			Tokens:   tokens,
+			Events:   events.NewBroker(),
+			Notifier: notifier,
+			Emails:   renderer,
+			Sealer:   sealer,
+		}
+	}
+
 	c := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},                             // Adjust as needed, "*" allows all origins
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},  // Allowed HTTP methods
@@ -42,10 +111,14 @@ func run() error {
 		AllowCredentials: true,
 	})
 
+	issuerDeps := deps(config.RoleIssuer, cfg.Agents.Issuer)
+	receiverDeps := deps(config.RoleHolder, cfg.Agents.Holder)
+	verifierDeps := deps(config.RoleVerifier, cfg.Agents.Verifier)
+
 	servers := []Server{
-		{"Issuer", ":1025", c.Handler(issuer.RegisterRoutes())},
-		{"Receiver", ":2025", c.Handler(receiver.RegisterRoutes())},
-		{"Verifier", ":3025", c.Handler(verifier.RegisterRoutes())},
+		{"Issuer", ":1025", c.Handler(issuer.RegisterRoutes(issuerDeps))},
+		{"Receiver", ":2025", c.Handler(receiver.RegisterRoutes(receiverDeps))},
+		{"Verifier", ":3025", c.Handler(verifier.RegisterRoutes(verifierDeps))},
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
@@ -61,6 +134,22 @@ func run() error {
 		}(s)
 	}
 
+	// Keep the stored connection states in step with each agent and check
+	// the other side of active connections is still there
+	runJob := func(run func(context.Context)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			run(ctx)
+		}()
+	}
+	for _, d := range []server.Dependencies{issuerDeps, receiverDeps, verifierDeps} {
+		runJob(connections.NewReconciler(d).Run)
+		runJob(connections.NewSweeper(d).Run)
+	}
+	// Only the issuer revokes credentials
+	runJob(revocations.NewPublisher(issuerDeps).Run)
+
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
@@ -74,6 +163,77 @@ func run() error {
 	return nil
 }
 
+// runMigrate implements the "migrate up|down|status" subcommand
+func runMigrate(args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s migrate up|down|status", os.Args[0])
+	}
+
+	cfg, err := config.Load(configPath())
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	store, err := db.NewStore(ctx, cfg.Database)
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	migrator, err := migrations.New(store.Pool())
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			log.Println("Database is up to date")
+		}
+		for _, m := range applied {
+			log.Printf("Applied migration %04d_%s", m.Version, m.Name)
+		}
+	case "down":
+		m, ok, err := migrator.Down(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			log.Println("No migration to roll back")
+			return nil
+		}
+		log.Printf("Rolled back migration %04d_%s", m.Version, m.Name)
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, appliedAt)
+		}
+	default:
+		return fmt.Errorf("unknown migrate command %q, expected up, down or status", args[0])
+	}
+	return nil
+}
+
+// configPath returns the config file location, overridable with CONFIG_FILE
+func configPath() string {
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		return path
+	}
+	return "./config.json"
+}
+
 func runServerWithRestart(ctx context.Context, s Server) {
 	for {
 		if err := runServer(ctx, s); err != nil {
@@ -94,6 +254,8 @@ func runServer(ctx context.Context, s Server) error {
 	server := &http.Server{
 		Addr:    s.addr,
 		Handler: s.handler,
+		// Requests inherit ctx so long-lived event streams end on shutdown
+		BaseContext: func(net.Listener) context.Context { return ctx },
 	}
 
 	serverErr := make(chan error, 1)
@@ -0,0 +1,262 @@
+// Package acapytest provides a fake agent for testing handlers without a
+// running ACA-Py.
+package acapytest
+
+import (
+	"context"
+	"digiauth/pkg/acapy"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+// ErrUnexpected is returned by the methods a test did not set up
+var ErrUnexpected = errors.New("acapytest: unexpected call")
+
+// Agent is an acapy.Agent whose methods call the function field of the same
+// name with a Func suffix. Methods whose field is nil fail with
+// ErrUnexpected, so a test only sets up the calls it expects. Every call is
+// recorded by method name.
+type Agent struct {
+	CreateInvitationFunc            func(ctx context.Context, multiUse bool) (acapy.InvitationResponse, error)
+	ReceiveInvitationFunc           func(ctx context.Context, invitation acapy.Invitation) (acapy.ConnRecord, error)
+	CreateOOBInvitationFunc         func(ctx context.Context, req acapy.OOBInvitationRequest) (acapy.OOBInvitationRecord, error)
+	ReceiveOOBInvitationFunc        func(ctx context.Context, invitation acapy.OOBInvitation) (acapy.OOBRecord, error)
+	DeleteOOBInvitationFunc         func(ctx context.Context, invitationMsgID string) error
+	ListConnectionsFunc             func(ctx context.Context) ([]acapy.ConnRecord, error)
+	ListConnectionsByInvitationFunc func(ctx context.Context, invitationMsgID string) ([]acapy.ConnRecord, error)
+	DeleteConnectionFunc            func(ctx context.Context, connectionID string) error
+	SetConnectionMetadataFunc       func(ctx context.Context, connectionID string, metadata map[string]interface{}) error
+	SendPingFunc                    func(ctx context.Context, connectionID string, req acapy.PingRequest) (acapy.PingResult, error)
+	SendBasicMessageFunc            func(ctx context.Context, connectionID string, req acapy.BasicMessageRequest) error
+	SendCredentialFunc              func(ctx context.Context, req acapy.CredentialSendRequest) (acapy.CredentialExchange, error)
+	RevokeFunc                      func(ctx context.Context, req acapy.RevokeRequest) error
+	CredentialRevocationRecordFunc  func(ctx context.Context, credExID string) (acapy.CredRevRecord, error)
+	PublishRevocationsFunc          func(ctx context.Context, rrid2crid map[string][]string) (map[string][]string, error)
+	CreateSchemaFunc                func(ctx context.Context, req acapy.SchemaSendRequest) (acapy.SchemaSendResult, error)
+	ListCreatedSchemasFunc          func(ctx context.Context) ([]string, error)
+	CreateCredentialDefinitionFunc  func(ctx context.Context, req acapy.CredentialDefinitionSendRequest) (acapy.CredentialDefinitionSendResult, error)
+	SendProofRequestFunc            func(ctx context.Context, req acapy.ProofRequest) (acapy.PresentationExchange, error)
+	ListPresentationRecordsFunc     func(ctx context.Context) ([]acapy.PresentationExchange, error)
+	GetPresentationRecordFunc       func(ctx context.Context, presExID string) (acapy.PresentationExchange, error)
+	SendPresentationFunc            func(ctx context.Context, presExID string, req acapy.PresentationSpec) (acapy.PresentationExchange, error)
+	ListCredentialsFunc             func(ctx context.Context) ([]acapy.Credential, error)
+
+	mu    sync.Mutex
+	calls []string
+}
+
+var _ acapy.Agent = (*Agent)(nil)
+
+// Calls returns the methods called so far, in order
+func (a *Agent) Calls() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.calls...)
+}
+
+// Called reports how many times method was called
+func (a *Agent) Called(method string) int {
+	n := 0
+	for _, call := range a.Calls() {
+		if call == method {
+			n++
+		}
+	}
+	return n
+}
+
+func (a *Agent) record(method string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.calls = append(a.calls, method)
+}
+
+// StatusError returns the error the client reports for an answer with status
+func StatusError(status int) error {
+	return &acapy.Error{Method: http.MethodGet, Path: "/", StatusCode: status, Body: http.StatusText(status)}
+}
+
+func (a *Agent) CreateInvitation(ctx context.Context, multiUse bool) (acapy.InvitationResponse, error) {
+	a.record("CreateInvitation")
+	if a.CreateInvitationFunc == nil {
+		return acapy.InvitationResponse{}, ErrUnexpected
+	}
+	return a.CreateInvitationFunc(ctx, multiUse)
+}
+
+func (a *Agent) ReceiveInvitation(ctx context.Context, invitation acapy.Invitation) (acapy.ConnRecord, error) {
+	a.record("ReceiveInvitation")
+	if a.ReceiveInvitationFunc == nil {
+		return acapy.ConnRecord{}, ErrUnexpected
+	}
+	return a.ReceiveInvitationFunc(ctx, invitation)
+}
+
+func (a *Agent) CreateOOBInvitation(ctx context.Context, req acapy.OOBInvitationRequest) (acapy.OOBInvitationRecord, error) {
+	a.record("CreateOOBInvitation")
+	if a.CreateOOBInvitationFunc == nil {
+		return acapy.OOBInvitationRecord{}, ErrUnexpected
+	}
+	return a.CreateOOBInvitationFunc(ctx, req)
+}
+
+func (a *Agent) ReceiveOOBInvitation(ctx context.Context, invitation acapy.OOBInvitation) (acapy.OOBRecord, error) {
+	a.record("ReceiveOOBInvitation")
+	if a.ReceiveOOBInvitationFunc == nil {
+		return acapy.OOBRecord{}, ErrUnexpected
+	}
+	return a.ReceiveOOBInvitationFunc(ctx, invitation)
+}
+
+func (a *Agent) DeleteOOBInvitation(ctx context.Context, invitationMsgID string) error {
+	a.record("DeleteOOBInvitation")
+	if a.DeleteOOBInvitationFunc == nil {
+		return ErrUnexpected
+	}
+	return a.DeleteOOBInvitationFunc(ctx, invitationMsgID)
+}
+
+func (a *Agent) ListConnections(ctx context.Context) ([]acapy.ConnRecord, error) {
+	a.record("ListConnections")
+	if a.ListConnectionsFunc == nil {
+		return nil, ErrUnexpected
+	}
+	return a.ListConnectionsFunc(ctx)
+}
+
+func (a *Agent) ListConnectionsByInvitation(ctx context.Context, invitationMsgID string) ([]acapy.ConnRecord, error) {
+	a.record("ListConnectionsByInvitation")
+	if a.ListConnectionsByInvitationFunc == nil {
+		return nil, ErrUnexpected
+	}
+	return a.ListConnectionsByInvitationFunc(ctx, invitationMsgID)
+}
+
+func (a *Agent) DeleteConnection(ctx context.Context, connectionID string) error {
+	a.record("DeleteConnection")
+	if a.DeleteConnectionFunc == nil {
+		return ErrUnexpected
+	}
+	return a.DeleteConnectionFunc(ctx, connectionID)
+}
+
+func (a *Agent) SetConnectionMetadata(ctx context.Context, connectionID string, metadata map[string]interface{}) error {
+	a.record("SetConnectionMetadata")
+	if a.SetConnectionMetadataFunc == nil {
+		return ErrUnexpected
+	}
+	return a.SetConnectionMetadataFunc(ctx, connectionID, metadata)
+}
+
+func (a *Agent) SendPing(ctx context.Context, connectionID string, req acapy.PingRequest) (acapy.PingResult, error) {
+	a.record("SendPing")
+	if a.SendPingFunc == nil {
+		return acapy.PingResult{}, ErrUnexpected
+	}
+	return a.SendPingFunc(ctx, connectionID, req)
+}
+
+func (a *Agent) SendBasicMessage(ctx context.Context, connectionID string, req acapy.BasicMessageRequest) error {
+	a.record("SendBasicMessage")
+	if a.SendBasicMessageFunc == nil {
+		return ErrUnexpected
+	}
+	return a.SendBasicMessageFunc(ctx, connectionID, req)
+}
+
+func (a *Agent) SendCredential(ctx context.Context, req acapy.CredentialSendRequest) (acapy.CredentialExchange, error) {
+	a.record("SendCredential")
+	if a.SendCredentialFunc == nil {
+		return acapy.CredentialExchange{}, ErrUnexpected
+	}
+	return a.SendCredentialFunc(ctx, req)
+}
+
+func (a *Agent) Revoke(ctx context.Context, req acapy.RevokeRequest) error {
+	a.record("Revoke")
+	if a.RevokeFunc == nil {
+		return ErrUnexpected
+	}
+	return a.RevokeFunc(ctx, req)
+}
+
+func (a *Agent) CredentialRevocationRecord(ctx context.Context, credExID string) (acapy.CredRevRecord, error) {
+	a.record("CredentialRevocationRecord")
+	if a.CredentialRevocationRecordFunc == nil {
+		return acapy.CredRevRecord{}, ErrUnexpected
+	}
+	return a.CredentialRevocationRecordFunc(ctx, credExID)
+}
+
+func (a *Agent) PublishRevocations(ctx context.Context, rrid2crid map[string][]string) (map[string][]string, error) {
+	a.record("PublishRevocations")
+	if a.PublishRevocationsFunc == nil {
+		return nil, ErrUnexpected
+	}
+	return a.PublishRevocationsFunc(ctx, rrid2crid)
+}
+
+func (a *Agent) CreateSchema(ctx context.Context, req acapy.SchemaSendRequest) (acapy.SchemaSendResult, error) {
+	a.record("CreateSchema")
+	if a.CreateSchemaFunc == nil {
+		return acapy.SchemaSendResult{}, ErrUnexpected
+	}
+	return a.CreateSchemaFunc(ctx, req)
+}
+
+func (a *Agent) ListCreatedSchemas(ctx context.Context) ([]string, error) {
+	a.record("ListCreatedSchemas")
+	if a.ListCreatedSchemasFunc == nil {
+		return nil, ErrUnexpected
+	}
+	return a.ListCreatedSchemasFunc(ctx)
+}
+
+func (a *Agent) CreateCredentialDefinition(ctx context.Context, req acapy.CredentialDefinitionSendRequest) (acapy.CredentialDefinitionSendResult, error) {
+	a.record("CreateCredentialDefinition")
+	if a.CreateCredentialDefinitionFunc == nil {
+		return acapy.CredentialDefinitionSendResult{}, ErrUnexpected
+	}
+	return a.CreateCredentialDefinitionFunc(ctx, req)
+}
+
+func (a *Agent) SendProofRequest(ctx context.Context, req acapy.ProofRequest) (acapy.PresentationExchange, error) {
+	a.record("SendProofRequest")
+	if a.SendProofRequestFunc == nil {
+		return acapy.PresentationExchange{}, ErrUnexpected
+	}
+	return a.SendProofRequestFunc(ctx, req)
+}
+
+func (a *Agent) ListPresentationRecords(ctx context.Context) ([]acapy.PresentationExchange, error) {
+	a.record("ListPresentationRecords")
+	if a.ListPresentationRecordsFunc == nil {
+		return nil, ErrUnexpected
+	}
+	return a.ListPresentationRecordsFunc(ctx)
+}
+
+func (a *Agent) GetPresentationRecord(ctx context.Context, presExID string) (acapy.PresentationExchange, error) {
+	a.record("GetPresentationRecord")
+	if a.GetPresentationRecordFunc == nil {
+		return acapy.PresentationExchange{}, ErrUnexpected
+	}
+	return a.GetPresentationRecordFunc(ctx, presExID)
+}
+
+func (a *Agent) SendPresentation(ctx context.Context, presExID string, req acapy.PresentationSpec) (acapy.PresentationExchange, error) {
+	a.record("SendPresentation")
+	if a.SendPresentationFunc == nil {
+		return acapy.PresentationExchange{}, ErrUnexpected
+	}
+	return a.SendPresentationFunc(ctx, presExID, req)
+}
+
+func (a *Agent) ListCredentials(ctx context.Context) ([]acapy.Credential, error) {
+	a.record("ListCredentials")
+	if a.ListCredentialsFunc == nil {
+		return nil, ErrUnexpected
+	}
+	return a.ListCredentialsFunc(ctx)
+}
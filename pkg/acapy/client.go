@@ -0,0 +1,273 @@
+package acapy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Agent is the subset of the ACA-Py admin API used by the DigiAuth servers.
+// Handlers depend on this interface so they can be exercised against a fake agent.
+type Agent interface {
+	CreateInvitation(ctx context.Context, multiUse bool) (InvitationResponse, error)
+	ReceiveInvitation(ctx context.Context, invitation Invitation) (ConnRecord, error)
+	CreateOOBInvitation(ctx context.Context, req OOBInvitationRequest) (OOBInvitationRecord, error)
+	ReceiveOOBInvitation(ctx context.Context, invitation OOBInvitation) (OOBRecord, error)
+	DeleteOOBInvitation(ctx context.Context, invitationMsgID string) error
+	ListConnections(ctx context.Context) ([]ConnRecord, error)
+	ListConnectionsByInvitation(ctx context.Context, invitationMsgID string) ([]ConnRecord, error)
+	DeleteConnection(ctx context.Context, connectionID string) error
+	SetConnectionMetadata(ctx context.Context, connectionID string, metadata map[string]interface{}) error
+	SendPing(ctx context.Context, connectionID string, req PingRequest) (PingResult, error)
+	SendBasicMessage(ctx context.Context, connectionID string, req BasicMessageRequest) error
+	SendCredential(ctx context.Context, req CredentialSendRequest) (CredentialExchange, error)
+	Revoke(ctx context.Context, req RevokeRequest) error
+	CredentialRevocationRecord(ctx context.Context, credExID string) (CredRevRecord, error)
+	PublishRevocations(ctx context.Context, rrid2crid map[string][]string) (map[string][]string, error)
+	CreateSchema(ctx context.Context, req SchemaSendRequest) (SchemaSendResult, error)
+	ListCreatedSchemas(ctx context.Context) ([]string, error)
+	CreateCredentialDefinition(ctx context.Context, req CredentialDefinitionSendRequest) (CredentialDefinitionSendResult, error)
+	SendProofRequest(ctx context.Context, req ProofRequest) (PresentationExchange, error)
+	ListPresentationRecords(ctx context.Context) ([]PresentationExchange, error)
+	GetPresentationRecord(ctx context.Context, presExID string) (PresentationExchange, error)
+	SendPresentation(ctx context.Context, presExID string, req PresentationSpec) (PresentationExchange, error)
+	ListCredentials(ctx context.Context) ([]Credential, error)
+}
+
+// Error is returned when the agent answers with a non-2xx status
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Body       string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("acapy: %s %s returned %d: %s", e.Method, e.Path, e.StatusCode, e.Body)
+}
+
+// StatusCode maps an error returned by the client to the status a handler
+// should answer with. Client errors reported by the agent are passed on,
+// anything else the agent rejected is a bad gateway.
+func StatusCode(err error) int {
+	var agentErr *Error
+	if errors.As(err, &agentErr) {
+		if agentErr.StatusCode >= 400 && agentErr.StatusCode < 500 {
+			return agentErr.StatusCode
+		}
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+// IsNotFound reports whether the agent answered that the record does not exist
+func IsNotFound(err error) bool {
+	var agentErr *Error
+	return errors.As(err, &agentErr) && agentErr.StatusCode == http.StatusNotFound
+}
+
+// Client talks to one agent's admin API
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+var _ Agent = (*Client)(nil)
+
+// NewClient returns a client for the admin API at baseURL. A nil httpClient
+// uses a default client with a 30 second timeout.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Client{baseURL: baseURL, httpClient: httpClient}
+}
+
+// CreateInvitation creates a legacy invitation. A multi-use invitation can be
+// accepted any number of times, each acceptance creates a new connection.
+func (c *Client) CreateInvitation(ctx context.Context, multiUse bool) (InvitationResponse, error) {
+	var res InvitationResponse
+	path := "/connections/create-invitation"
+	if multiUse {
+		path += "?multi_use=true"
+	}
+	err := c.do(ctx, http.MethodPost, path, nil, &res)
+	return res, err
+}
+
+func (c *Client) ReceiveInvitation(ctx context.Context, invitation Invitation) (ConnRecord, error) {
+	var res ConnRecord
+	err := c.do(ctx, http.MethodPost, "/connections/receive-invitation", invitation, &res)
+	return res, err
+}
+
+// ListConnections returns every connection record the agent has
+func (c *Client) ListConnections(ctx context.Context) ([]ConnRecord, error) {
+	var res struct {
+		Results []ConnRecord `json:"results"`
+	}
+	err := c.do(ctx, http.MethodGet, "/connections", nil, &res)
+	return res.Results, err
+}
+
+// DeleteConnection removes the connection record, which also makes a pending
+// invitation for it unusable
+func (c *Client) DeleteConnection(ctx context.Context, connectionID string) error {
+	return c.do(ctx, http.MethodDelete, "/connections/"+url.PathEscape(connectionID), nil, nil)
+}
+
+// SetConnectionMetadata sets the given keys of the connection's metadata.
+// Keys left out keep their value, a nil value clears the key.
+func (c *Client) SetConnectionMetadata(ctx context.Context, connectionID string, metadata map[string]interface{}) error {
+	body := map[string]interface{}{"metadata": metadata}
+	return c.do(ctx, http.MethodPost, "/connections/"+url.PathEscape(connectionID)+"/metadata", body, nil)
+}
+
+// SendPing sends a trust ping over the connection. The answer, if any, comes
+// back on the ping webhook topic.
+func (c *Client) SendPing(ctx context.Context, connectionID string, req PingRequest) (PingResult, error) {
+	var res PingResult
+	err := c.do(ctx, http.MethodPost, "/connections/"+url.PathEscape(connectionID)+"/send-ping", req, &res)
+	return res, err
+}
+
+// SendBasicMessage sends a text message over the connection
+func (c *Client) SendBasicMessage(ctx context.Context, connectionID string, req BasicMessageRequest) error {
+	return c.do(ctx, http.MethodPost, "/connections/"+url.PathEscape(connectionID)+"/send-message", req, nil)
+}
+
+func (c *Client) SendCredential(ctx context.Context, req CredentialSendRequest) (CredentialExchange, error) {
+	var res CredentialExchange
+	err := c.do(ctx, http.MethodPost, "/issue-credential-2.0/send", req, &res)
+	return res, err
+}
+
+// Revoke revokes an issued credential. Unless published right away the
+// revocation waits in the agent's pending list for the next publication.
+func (c *Client) Revoke(ctx context.Context, req RevokeRequest) error {
+	return c.do(ctx, http.MethodPost, "/revocation/revoke", req, nil)
+}
+
+// CredentialRevocationRecord returns the revocation registry entry the
+// agent gave the credential of an exchange
+func (c *Client) CredentialRevocationRecord(ctx context.Context, credExID string) (CredRevRecord, error) {
+	var res struct {
+		Result CredRevRecord `json:"result"`
+	}
+	err := c.do(ctx, http.MethodGet, "/revocation/credential-record?cred_ex_id="+url.QueryEscape(credExID), nil, &res)
+	return res.Result, err
+}
+
+// PublishRevocations publishes the pending revocations of the given
+// registries, keyed by rev_reg_id, in one ledger update per registry. It
+// returns the entries the agent actually published.
+func (c *Client) PublishRevocations(ctx context.Context, rrid2crid map[string][]string) (map[string][]string, error) {
+	body := map[string]interface{}{"rrid2crid": rrid2crid}
+	var res struct {
+		RRID2CRID map[string][]string `json:"rrid2crid"`
+	}
+	err := c.do(ctx, http.MethodPost, "/revocation/publish-revocations", body, &res)
+	return res.RRID2CRID, err
+}
+
+func (c *Client) CreateSchema(ctx context.Context, req SchemaSendRequest) (SchemaSendResult, error) {
+	var res SchemaSendResult
+	err := c.do(ctx, http.MethodPost, "/schemas", req, &res)
+	return res, err
+}
+
+func (c *Client) ListCreatedSchemas(ctx context.Context) ([]string, error) {
+	var res struct {
+		SchemaIDs []string `json:"schema_ids"`
+	}
+	err := c.do(ctx, http.MethodGet, "/schemas/created", nil, &res)
+	return res.SchemaIDs, err
+}
+
+func (c *Client) CreateCredentialDefinition(ctx context.Context, req CredentialDefinitionSendRequest) (CredentialDefinitionSendResult, error) {
+	var res CredentialDefinitionSendResult
+	err := c.do(ctx, http.MethodPost, "/credential-definitions", req, &res)
+	return res, err
+}
+
+func (c *Client) SendProofRequest(ctx context.Context, req ProofRequest) (PresentationExchange, error) {
+	var res PresentationExchange
+	err := c.do(ctx, http.MethodPost, "/present-proof-2.0/send-request", req, &res)
+	return res, err
+}
+
+func (c *Client) ListPresentationRecords(ctx context.Context) ([]PresentationExchange, error) {
+	var res struct {
+		Results []PresentationExchange `json:"results"`
+	}
+	err := c.do(ctx, http.MethodGet, "/present-proof-2.0/records", nil, &res)
+	return res.Results, err
+}
+
+func (c *Client) GetPresentationRecord(ctx context.Context, presExID string) (PresentationExchange, error) {
+	var res PresentationExchange
+	err := c.do(ctx, http.MethodGet, "/present-proof-2.0/records/"+url.PathEscape(presExID), nil, &res)
+	return res, err
+}
+
+func (c *Client) SendPresentation(ctx context.Context, presExID string, req PresentationSpec) (PresentationExchange, error) {
+	var res PresentationExchange
+	path := "/present-proof-2.0/records/" + url.PathEscape(presExID) + "/send-presentation"
+	err := c.do(ctx, http.MethodPost, path, req, &res)
+	return res, err
+}
+
+func (c *Client) ListCredentials(ctx context.Context) ([]Credential, error) {
+	var res struct {
+		Results []Credential `json:"results"`
+	}
+	err := c.do(ctx, http.MethodGet, "/credentials", nil, &res)
+	return res.Results, err
+}
+
+// do sends body as JSON (when non-nil) and decodes a 2xx response into out
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("acapy: marshal %s request: %w", path, err)
+		}
+		reqBody = bytes.NewReader(payload)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("acapy: %s %s: %w", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("acapy: read %s response: %w", path, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &Error{Method: method, Path: path, StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	if out == nil || len(respBody) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("acapy: parse %s response: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,223 @@
+package acapy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Invitation protocols the servers can create invitations with
+const (
+	// ProtocolOutOfBand uses /out-of-band (RFC 0434)
+	ProtocolOutOfBand = "out-of-band"
+	// ProtocolConnections uses the legacy /connections invitations (RFC 0160)
+	// for agents that predate out-of-band support
+	ProtocolConnections = "connections"
+)
+
+// Handshake protocols offered in out-of-band invitations, preferred first
+const (
+	HandshakeDIDExchange = "https://didcomm.org/didexchange/1.0"
+	HandshakeConnections = "https://didcomm.org/connections/1.0"
+)
+
+// OOBAttachment references a record of the agent to send along with an
+// out-of-band invitation. Type is "credential-offer" or "present-proof".
+type OOBAttachment struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+}
+
+// OOBInvitationRequest describes an out-of-band invitation to create.
+// MultiUse is sent as a query parameter.
+type OOBInvitationRequest struct {
+	Alias              string          `json:"alias,omitempty"`
+	HandshakeProtocols []string        `json:"handshake_protocols,omitempty"`
+	Attachments        []OOBAttachment `json:"attachments,omitempty"`
+	UsePublicDID       bool            `json:"use_public_did"`
+	MultiUse           bool            `json:"-"`
+}
+
+// OOBInvitation is an out-of-band invitation message. Services are either
+// DIDs or inline service blocks, so they are kept as raw JSON.
+type OOBInvitation struct {
+	Type               string            `json:"@type"`
+	ID                 string            `json:"@id"`
+	Label              string            `json:"label,omitempty"`
+	HandshakeProtocols []string          `json:"handshake_protocols,omitempty"`
+	Services           []json.RawMessage `json:"services"`
+	Requests           []json.RawMessage `json:"requests~attach,omitempty"`
+	Accept             []string          `json:"accept,omitempty"`
+}
+
+// OOBInvitationRecord is the inviter's record of a created out-of-band invitation
+type OOBInvitationRecord struct {
+	InviMsgID     string        `json:"invi_msg_id"`
+	OobID         string        `json:"oob_id"`
+	State         string        `json:"state"`
+	Invitation    OOBInvitation `json:"invitation"`
+	InvitationURL string        `json:"invitation_url"`
+}
+
+// OOBRecord is the invitee's record of a received out-of-band invitation
+type OOBRecord struct {
+	OobID        string `json:"oob_id"`
+	State        string `json:"state"`
+	InviMsgID    string `json:"invi_msg_id"`
+	ConnectionID string `json:"connection_id"`
+	Role         string `json:"role"`
+}
+
+func (c *Client) CreateOOBInvitation(ctx context.Context, req OOBInvitationRequest) (OOBInvitationRecord, error) {
+	var res OOBInvitationRecord
+	path := "/out-of-band/create-invitation"
+	if req.MultiUse {
+		path += "?multi_use=true"
+	}
+	err := c.do(ctx, http.MethodPost, path, req, &res)
+	return res, err
+}
+
+func (c *Client) ReceiveOOBInvitation(ctx context.Context, invitation OOBInvitation) (OOBRecord, error) {
+	var res OOBRecord
+	err := c.do(ctx, http.MethodPost, "/out-of-band/receive-invitation", invitation, &res)
+	return res, err
+}
+
+// DeleteOOBInvitation removes the inviter's record of the out-of-band
+// invitation invitationMsgID, so it can no longer be accepted
+func (c *Client) DeleteOOBInvitation(ctx context.Context, invitationMsgID string) error {
+	return c.do(ctx, http.MethodDelete, "/out-of-band/invitations/"+url.PathEscape(invitationMsgID), nil, nil)
+}
+
+// ListConnectionsByInvitation returns the connections created for the
+// invitation message invitationMsgID
+func (c *Client) ListConnectionsByInvitation(ctx context.Context, invitationMsgID string) ([]ConnRecord, error) {
+	var res struct {
+		Results []ConnRecord `json:"results"`
+	}
+	path := "/connections?invitation_msg_id=" + url.QueryEscape(invitationMsgID)
+	err := c.do(ctx, http.MethodGet, path, nil, &res)
+	return res.Results, err
+}
+
+type messageHeader struct {
+	Type string `json:"@type"`
+	ID   string `json:"@id"`
+}
+
+// IsOutOfBand reports whether the raw invitation is an out-of-band one, going
+// by its @type such as https://didcomm.org/out-of-band/1.1/invitation
+func IsOutOfBand(invitation json.RawMessage) bool {
+	var msg messageHeader
+	if err := json.Unmarshal(invitation, &msg); err != nil {
+		return false
+	}
+	return strings.Contains(msg.Type, "/out-of-band/")
+}
+
+// InvitationID returns the @id of a raw invitation of either protocol
+func InvitationID(invitation json.RawMessage) string {
+	var msg messageHeader
+	if err := json.Unmarshal(invitation, &msg); err != nil {
+		return ""
+	}
+	return msg.ID
+}
+
+// CreatedInvitation is an invitation made with either protocol and the
+// connection record the agent will complete when it is accepted. Connections
+// made from a multi-use invitation are new records sharing its message ID or
+// recipient key.
+type CreatedInvitation struct {
+	ConnectionID    string
+	Invitation      json.RawMessage
+	InvitationMsgID string
+	InvitationKey   string
+}
+
+// NewInvitation creates a single-use invitation using protocol. Attachments
+// are only supported by out-of-band invitations.
+func NewInvitation(ctx context.Context, agent Agent, protocol string, attachments []OOBAttachment) (CreatedInvitation, error) {
+	return newInvitation(ctx, agent, protocol, attachments, false)
+}
+
+// NewMultiUseInvitation creates an invitation any number of holders can accept
+func NewMultiUseInvitation(ctx context.Context, agent Agent, protocol string) (CreatedInvitation, error) {
+	return newInvitation(ctx, agent, protocol, nil, true)
+}
+
+func newInvitation(ctx context.Context, agent Agent, protocol string, attachments []OOBAttachment, multiUse bool) (CreatedInvitation, error) {
+	if protocol == ProtocolConnections {
+		if len(attachments) > 0 {
+			return CreatedInvitation{}, errors.New("acapy: attachments need out-of-band invitations")
+		}
+		res, err := agent.CreateInvitation(ctx, multiUse)
+		if err != nil {
+			return CreatedInvitation{}, err
+		}
+		raw, err := json.Marshal(res.Invitation)
+		created := CreatedInvitation{ConnectionID: res.ConnectionID, Invitation: raw, InvitationMsgID: res.Invitation.ID}
+		if len(res.Invitation.RecipientKeys) > 0 {
+			created.InvitationKey = res.Invitation.RecipientKeys[0]
+		}
+		return created, err
+	}
+
+	record, err := agent.CreateOOBInvitation(ctx, OOBInvitationRequest{
+		HandshakeProtocols: []string{HandshakeDIDExchange, HandshakeConnections},
+		Attachments:        attachments,
+		MultiUse:           multiUse,
+	})
+	if err != nil {
+		return CreatedInvitation{}, err
+	}
+	// The invitation record does not carry the connection, look it up by the
+	// invitation message it was created for
+	connections, err := agent.ListConnectionsByInvitation(ctx, record.InviMsgID)
+	if err != nil {
+		return CreatedInvitation{}, err
+	}
+	if len(connections) == 0 {
+		return CreatedInvitation{}, errors.New("acapy: no connection was created for invitation " + record.InviMsgID)
+	}
+	raw, err := json.Marshal(record.Invitation)
+	return CreatedInvitation{
+		ConnectionID:    connections[0].ConnectionID,
+		Invitation:      raw,
+		InvitationMsgID: record.InviMsgID,
+		InvitationKey:   connections[0].InvitationKey,
+	}, err
+}
+
+// AcceptInvitation receives an invitation of either protocol. It returns the
+// connection it creates and the agent's record, to pass on to the caller.
+func AcceptInvitation(ctx context.Context, agent Agent, invitation json.RawMessage) (string, interface{}, error) {
+	if IsOutOfBand(invitation) {
+		var msg OOBInvitation
+		if err := json.Unmarshal(invitation, &msg); err != nil {
+			return "", nil, err
+		}
+		record, err := agent.ReceiveOOBInvitation(ctx, msg)
+		if err != nil {
+			return "", nil, err
+		}
+		if record.ConnectionID == "" {
+			return "", nil, errors.New("acapy: out-of-band invitation " + record.InviMsgID + " did not start a connection")
+		}
+		return record.ConnectionID, record, nil
+	}
+
+	var msg Invitation
+	if err := json.Unmarshal(invitation, &msg); err != nil {
+		return "", nil, err
+	}
+	record, err := agent.ReceiveInvitation(ctx, msg)
+	if err != nil {
+		return "", nil, err
+	}
+	return record.ConnectionID, record, nil
+}
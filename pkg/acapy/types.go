@@ -0,0 +1,278 @@
+package acapy
+
+// Connection invitation (RFC 0160)
+type Invitation struct {
+	Type            string   `json:"@type"`
+	ID              string   `json:"@id"`
+	Label           string   `json:"label"`
+	RecipientKeys   []string `json:"recipientKeys"`
+	ServiceEndpoint string   `json:"serviceEndpoint"`
+}
+
+type InvitationResponse struct {
+	ConnectionID  string     `json:"connection_id"`
+	Invitation    Invitation `json:"invitation"`
+	InvitationURL string     `json:"invitation_url"`
+}
+
+// ConnRecord is the agent's view of a connection
+type ConnRecord struct {
+	State              string `json:"state"`
+	CreatedAt          string `json:"created_at"`
+	UpdatedAt          string `json:"updated_at"`
+	ConnectionID       string `json:"connection_id"`
+	MyDID              string `json:"my_did"`
+	TheirDID           string `json:"their_did"`
+	TheirLabel         string `json:"their_label"`
+	TheirRole          string `json:"their_role"`
+	ConnectionProtocol string `json:"connection_protocol"`
+	RFC23State         string `json:"rfc23_state"`
+	InvitationKey      string `json:"invitation_key"`
+	InvitationMsgID    string `json:"invitation_msg_id"`
+	RequestID          string `json:"request_id"`
+	Accept             string `json:"accept"`
+	InvitationMode     string `json:"invitation_mode"`
+}
+
+// Credential issuance (issue-credential 2.0)
+type CredentialAttribute struct {
+	MimeType string `json:"mime-type,omitempty"`
+	Name     string `json:"name"`
+	Value    string `json:"value"`
+}
+
+type CredentialPreview struct {
+	Type       string                `json:"@type"`
+	Attributes []CredentialAttribute `json:"attributes"`
+}
+
+type IndyFilter struct {
+	CredDefID       string `json:"cred_def_id,omitempty"`
+	SchemaID        string `json:"schema_id,omitempty"`
+	SchemaIssuerDID string `json:"schema_issuer_did,omitempty"`
+	SchemaName      string `json:"schema_name,omitempty"`
+	SchemaVersion   string `json:"schema_version,omitempty"`
+	IssuerDID       string `json:"issuer_did,omitempty"`
+}
+
+type CredentialFilter struct {
+	Indy IndyFilter `json:"indy"`
+}
+
+type CredentialSendRequest struct {
+	ConnectionID      string            `json:"connection_id"`
+	Filter            CredentialFilter  `json:"filter"`
+	CredentialPreview CredentialPreview `json:"credential_preview"`
+	AutoRemove        bool              `json:"auto_remove"`
+	Comment           string            `json:"comment,omitempty"`
+	Trace             bool              `json:"trace"`
+}
+
+type CredentialExchange struct {
+	CredExID     string              `json:"cred_ex_id"`
+	ConnectionID string              `json:"connection_id"`
+	ThreadID     string              `json:"thread_id"`
+	State        string              `json:"state"`
+	Role         string              `json:"role"`
+	Initiator    string              `json:"initiator"`
+	CreatedAt    string              `json:"created_at"`
+	UpdatedAt    string              `json:"updated_at"`
+	CredPreview  *CredentialPreview  `json:"cred_preview,omitempty"`
+	ByFormat     *CredentialByFormat `json:"by_format,omitempty"`
+}
+
+// CredentialByFormat holds the format specific parts of an exchange, of
+// which only the schema and credential definition of the offer are read
+type CredentialByFormat struct {
+	CredOffer struct {
+		Indy IndyFilter `json:"indy"`
+	} `json:"cred_offer"`
+}
+
+// Credential is an entry of the holder wallet
+type Credential struct {
+	Referent  string            `json:"referent"`
+	SchemaID  string            `json:"schema_id"`
+	CredDefID string            `json:"cred_def_id"`
+	RevRegID  string            `json:"rev_reg_id"`
+	CredRevID string            `json:"cred_rev_id"`
+	Attrs     map[string]string `json:"attrs"`
+}
+
+// Schemas and credential definitions
+type SchemaSendRequest struct {
+	Attributes    []string `json:"attributes"`
+	SchemaName    string   `json:"schema_name"`
+	SchemaVersion string   `json:"schema_version"`
+}
+
+type SchemaSendResult struct {
+	SchemaID string `json:"schema_id"`
+}
+
+type CredentialDefinitionSendRequest struct {
+	SchemaID               string `json:"schema_id"`
+	Tag                    string `json:"tag"`
+	SupportRevocation      bool   `json:"support_revocation"`
+	RevocationRegistrySize int    `json:"revocation_registry_size,omitempty"`
+}
+
+type CredentialDefinitionSendResult struct {
+	CredentialDefinitionID string `json:"credential_definition_id"`
+}
+
+// Proof requests and presentations (present-proof 2.0)
+type Restriction struct {
+	CredDefID string `json:"cred_def_id,omitempty"`
+	SchemaID  string `json:"schema_id,omitempty"`
+}
+
+type IndyRequestedAttribute struct {
+	Name         string        `json:"name"`
+	Restrictions []Restriction `json:"restrictions,omitempty"`
+}
+
+type IndyRequestedPredicate struct {
+	Name         string        `json:"name"`
+	PType        string        `json:"p_type"`
+	PValue       int           `json:"p_value"`
+	Restrictions []Restriction `json:"restrictions,omitempty"`
+}
+
+type IndyProofRequest struct {
+	Name                string                            `json:"name"`
+	Version             string                            `json:"version"`
+	RequestedAttributes map[string]IndyRequestedAttribute `json:"requested_attributes"`
+	RequestedPredicates map[string]IndyRequestedPredicate `json:"requested_predicates"`
+}
+
+type PresentationRequest struct {
+	Indy IndyProofRequest `json:"indy"`
+}
+
+type ProofRequest struct {
+	ConnectionID        string              `json:"connection_id"`
+	PresentationRequest PresentationRequest `json:"presentation_request"`
+	Trace               bool                `json:"trace"`
+}
+
+type IndyRequestedCredential struct {
+	CredID   string `json:"cred_id"`
+	Revealed bool   `json:"revealed"`
+}
+
+type IndyRequestedPredicateCredential struct {
+	CredID    string `json:"cred_id"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+}
+
+type IndyPresentationSpec struct {
+	RequestedAttributes    map[string]IndyRequestedCredential          `json:"requested_attributes"`
+	RequestedPredicates    map[string]IndyRequestedPredicateCredential `json:"requested_predicates"`
+	SelfAttestedAttributes map[string]string                           `json:"self_attested_attributes"`
+}
+
+type PresentationSpec struct {
+	Indy       IndyPresentationSpec `json:"indy"`
+	AutoRemove bool                 `json:"auto_remove"`
+	Trace      bool                 `json:"trace"`
+}
+
+type PresentationExchange struct {
+	PresExID     string `json:"pres_ex_id"`
+	ConnectionID string `json:"connection_id"`
+	ThreadID     string `json:"thread_id"`
+	State        string `json:"state"`
+	Role         string `json:"role"`
+	Verified     string `json:"verified"`
+	CreatedAt    string `json:"created_at"`
+	UpdatedAt    string `json:"updated_at"`
+}
+
+// RevocationRegistry is the issuer revocation registry record sent on the
+// revocation_registry webhook topic
+type RevocationRegistry struct {
+	RevocRegID string `json:"revoc_reg_id"`
+	CredDefID  string `json:"cred_def_id"`
+	State      string `json:"state"`
+	MaxCredNum int    `json:"max_cred_num"`
+	CreatedAt  string `json:"created_at"`
+	UpdatedAt  string `json:"updated_at"`
+}
+
+// Trust ping (RFC 0048)
+type PingRequest struct {
+	Comment string `json:"comment,omitempty"`
+}
+
+type PingResult struct {
+	ThreadID string `json:"thread_id"`
+}
+
+// Ping is sent on the ping webhook topic by agents started with
+// --monitor-ping. State is "received" for pings from the other party and
+// "response_received" for answers to ours.
+type Ping struct {
+	Comment      string `json:"comment"`
+	ConnectionID string `json:"connection_id"`
+	Responded    bool   `json:"responded"`
+	State        string `json:"state"`
+	ThreadID     string `json:"thread_id"`
+}
+
+// Ping states
+const (
+	PingReceived         = "received"
+	PingResponseReceived = "response_received"
+)
+
+// TimeLayout is how the agent formats timestamps such as sent_time
+const TimeLayout = "2006-01-02 15:04:05.999999Z"
+
+// Basic messages (RFC 0095)
+type BasicMessageRequest struct {
+	Content string `json:"content"`
+}
+
+// BasicMessage is sent on the basicmessages webhook topic when a message
+// arrives over a connection
+type BasicMessage struct {
+	ConnectionID string `json:"connection_id"`
+	MessageID    string `json:"message_id"`
+	Content      string `json:"content"`
+	State        string `json:"state"`
+	SentTime     string `json:"sent_time"`
+}
+
+// Revocation. A credential is named by its cred_ex_id or by its registry and
+// index; notifying the holder needs the connection and thread it was issued on.
+type RevokeRequest struct {
+	CredExID      string `json:"cred_ex_id,omitempty"`
+	RevRegID      string `json:"rev_reg_id,omitempty"`
+	CredRevID     string `json:"cred_rev_id,omitempty"`
+	Publish       bool   `json:"publish"`
+	Notify        bool   `json:"notify"`
+	NotifyVersion string `json:"notify_version,omitempty"`
+	ConnectionID  string `json:"connection_id,omitempty"`
+	ThreadID      string `json:"thread_id,omitempty"`
+	Comment       string `json:"comment,omitempty"`
+}
+
+// CredRevRecord is the agent's record of an issued, revocable credential
+type CredRevRecord struct {
+	CredExID  string `json:"cred_ex_id"`
+	RevRegID  string `json:"rev_reg_id"`
+	CredRevID string `json:"cred_rev_id"`
+	State     string `json:"state"`
+}
+
+// CredentialExchangeIndy is sent on the issue_credential_v2_0_indy webhook
+// topic once an indy credential is issued, with the revocation registry
+// entry it was given. On the holder side CredIDStored is the wallet id of
+// the stored credential.
+type CredentialExchangeIndy struct {
+	CredExID     string `json:"cred_ex_id"`
+	RevRegID     string `json:"rev_reg_id"`
+	CredRevID    string `json:"cred_rev_id"`
+	CredIDStored string `json:"cred_id_stored"`
+}
@@ -0,0 +1,15 @@
+package acapy
+
+// Webhook topics posted by the agent to <webhook-url>/topic/<topic>/
+const (
+	TopicConnections            = "connections"
+	TopicIssueCredentialV20     = "issue_credential_v2_0"
+	TopicIssueCredentialV20Indy = "issue_credential_v2_0_indy"
+	TopicPresentProofV20        = "present_proof_v2_0"
+	TopicRevocationRegistry     = "revocation_registry"
+	TopicPing                   = "ping"
+	TopicBasicMessages          = "basicmessages"
+)
+
+// APIKeyHeader carries the key configured with --webhook-url <url>#<key>
+const APIKeyHeader = "X-Api-Key"
@@ -0,0 +1,197 @@
+package account
+
+import (
+	"context"
+	models "digiauth/pkg/main-app/account/models"
+	"digiauth/pkg/main-app/auth"
+	"digiauth/pkg/main-app/db"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	minPasswordLength = 8
+	// bcrypt only looks at the first 72 bytes of a password
+	maxPasswordLength = 72
+)
+
+type Controller struct {
+	store  *db.Store
+	tokens *auth.TokenManager
+}
+
+func NewController(store *db.Store, tokens *auth.TokenManager) *Controller {
+	return &Controller{store: store, tokens: tokens}
+}
+
+func (c *Controller) Signup(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var req models.SignupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	email, err := normalizeEmail(req.Email)
+	if err != nil {
+		http.Error(w, "Invalid email address", http.StatusBadRequest)
+		return
+	}
+	if len(req.Password) < minPasswordLength || len(req.Password) > maxPasswordLength {
+		http.Error(w, "Password must be between 8 and 72 characters", http.StatusBadRequest)
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		log.Println("Error hashing password : ", err.Error())
+		http.Error(w, "Failed to create account", http.StatusInternalServerError)
+		return
+	}
+
+	user, err := c.store.CreateUser(ctx, sql.CreateUserParams{
+		Email:        email,
+		Name:         strings.TrimSpace(req.Name),
+		PasswordHash: string(hash),
+	})
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			http.Error(w, "An account with this email already exists", http.StatusConflict)
+			return
+		}
+		log.Println("Error inserting user to db : ", err.Error())
+		http.Error(w, "Error inserting user to db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"user": toProfile(user)})
+}
+
+func (c *Controller) Login(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var req models.LoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	user, err := c.store.GetUserByEmail(ctx, strings.TrimSpace(req.Email))
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Println("Error fetching user from db : ", err.Error())
+		http.Error(w, "Error fetching user from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Unknown emails, accounts without a password and wrong passwords all get
+	// the same answer so the endpoint does not reveal which accounts exist
+	if err != nil || user.PasswordHash == "" ||
+		bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		http.Error(w, "Invalid email or password", http.StatusUnauthorized)
+		return
+	}
+
+	tokens, err := c.tokens.Issue(auth.User{ID: user.ID, Email: user.Email})
+	if err != nil {
+		log.Println("Error signing tokens : ", err.Error())
+		http.Error(w, "Failed to log in", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"user": toProfile(user), "tokens": tokens})
+}
+
+// Refresh exchanges a valid refresh token for a new token pair
+func (c *Controller) Refresh(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var req models.RefreshRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	claimed, err := c.tokens.Parse(req.RefreshToken, auth.RefreshToken)
+	if err != nil {
+		http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+
+	// Reload the account so tokens carry its current email
+	user, err := c.store.GetUserByID(ctx, claimed.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Invalid or expired refresh token", http.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		log.Println("Error fetching user from db : ", err.Error())
+		http.Error(w, "Error fetching user from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	tokens, err := c.tokens.Issue(auth.User{ID: user.ID, Email: user.Email})
+	if err != nil {
+		log.Println("Error signing tokens : ", err.Error())
+		http.Error(w, "Failed to refresh tokens", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"tokens": tokens})
+}
+
+func (c *Controller) Profile(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	caller, _ := auth.UserFromContext(r.Context())
+	user, err := c.store.GetUserByID(ctx, caller.ID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "User not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Error fetching user from db : ", err.Error())
+		http.Error(w, "Error fetching user from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"user": toProfile(user)})
+}
+
+func toProfile(user sql.User) models.Profile {
+	return models.Profile{
+		ID:        user.ID,
+		Email:     user.Email,
+		Name:      user.Name,
+		CreatedAt: user.CreatedAt.Time,
+	}
+}
+
+// normalizeEmail validates a bare address such as alice@example.com and lowercases it
+func normalizeEmail(email string) (string, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(email))
+	if err != nil || addr.Name != "" || addr.Address != strings.TrimSpace(email) {
+		return "", errors.New("invalid email address")
+	}
+	return strings.ToLower(addr.Address), nil
+}
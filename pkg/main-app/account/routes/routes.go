@@ -0,0 +1,20 @@
+package account
+
+import (
+	controllers "digiauth/pkg/main-app/account/controllers"
+	"digiauth/pkg/main-app/auth"
+	"digiauth/pkg/main-app/db"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes adds the account endpoints to a role's routers: signup, login
+// and refresh on the public one, the rest behind authentication. Accounts are
+// shared, so a user signed up on one server can log in on any of them.
+func RegisterRoutes(public, protected *mux.Router, store *db.Store, tokens *auth.TokenManager) {
+	controller := controllers.NewController(store, tokens)
+	public.HandleFunc("/signup", controller.Signup).Methods("POST")
+	public.HandleFunc("/login", controller.Login).Methods("POST")
+	public.HandleFunc("/refresh", controller.Refresh).Methods("POST")
+	protected.HandleFunc("/profile", controller.Profile).Methods("GET")
+}
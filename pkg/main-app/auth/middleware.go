@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// User is the authenticated caller of a request
+type User struct {
+	ID    int64
+	Email string
+}
+
+type contextKey struct{}
+
+// Middleware rejects requests without a valid access token and stores the
+// authenticated user in the request context
+func (m *TokenManager) Middleware(next http.Handler) http.Handler {
+	return m.authenticate(next, bearerToken)
+}
+
+// StreamMiddleware is Middleware for event streams. Browsers cannot set
+// headers on an EventSource, so the token may also be passed as
+// ?access_token=. Only mount it on the event stream route, tokens in URLs
+// end up in access logs.
+func (m *TokenManager) StreamMiddleware(next http.Handler) http.Handler {
+	return m.authenticate(next, func(r *http.Request) (string, bool) {
+		if r.Header.Get("Authorization") == "" && r.Method == http.MethodGet {
+			token := r.URL.Query().Get("access_token")
+			return token, token != ""
+		}
+		return bearerToken(r)
+	})
+}
+
+func (m *TokenManager) authenticate(next http.Handler, tokenFrom func(r *http.Request) (string, bool)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := tokenFrom(r)
+		if !ok {
+			unauthorized(w, "Missing bearer token")
+			return
+		}
+		user, err := m.Parse(token, AccessToken)
+		if err != nil {
+			unauthorized(w, "Invalid or expired token")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithUser(r.Context(), user)))
+	})
+}
+
+func WithUser(ctx context.Context, user User) context.Context {
+	return context.WithValue(ctx, contextKey{}, user)
+}
+
+// UserFromContext returns the user stored by Middleware
+func UserFromContext(ctx context.Context) (User, bool) {
+	user, ok := ctx.Value(contextKey{}).(User)
+	return user, ok
+}
+
+// bearerToken reads the Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	header := r.Header.Get("Authorization")
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+func unauthorized(w http.ResponseWriter, message string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="digiauth"`)
+	WriteError(w, http.StatusUnauthorized, "unauthorized", message)
+}
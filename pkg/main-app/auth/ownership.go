@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrForbidden = errors.New("connection does not belong to the caller")
+
+// ConnectionStore is the lookup needed to check who owns a connection
+type ConnectionStore interface {
+	GetAuthorizedConnection(ctx context.Context, arg sql.GetAuthorizedConnectionParams) (sql.Connection, error)
+}
+
+// AuthorizeConnection returns ErrForbidden unless user owns connectionID on
+// the agent of role and has not deleted it. Unknown connections are reported
+// the same way so callers cannot probe for other users' connection IDs.
+func AuthorizeConnection(ctx context.Context, store ConnectionStore, user User, role, connectionID string) error {
+	if connectionID == "" {
+		return ErrForbidden
+	}
+	_, err := store.GetAuthorizedConnection(ctx, sql.GetAuthorizedConnectionParams{
+		ConnectionID: connectionID,
+		UserID:       user.ID,
+		Role:         role,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrForbidden
+	}
+	return err
+}
+
+// WriteAuthorizationError answers a failed AuthorizeConnection
+func WriteAuthorizationError(w http.ResponseWriter, err error) {
+	if errors.Is(err, ErrForbidden) {
+		WriteError(w, http.StatusForbidden, "forbidden", "You do not have access to this connection")
+		return
+	}
+	log.Println("Error checking connection ownership : ", err.Error())
+	WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to check connection ownership")
+}
@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"context"
+	"digiauth/pkg/main-app/config"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// fakeConnections answers GetAuthorizedConnection the way the query does:
+// the row must belong to the user, be on the role's agent and not be deleted
+type fakeConnections struct {
+	rows []sql.Connection
+	err  error
+}
+
+func (s fakeConnections) GetAuthorizedConnection(ctx context.Context, arg sql.GetAuthorizedConnectionParams) (sql.Connection, error) {
+	if s.err != nil {
+		return sql.Connection{}, s.err
+	}
+	for _, row := range s.rows {
+		if row.ConnectionID == arg.ConnectionID && row.ID == arg.UserID && row.Role == arg.Role && !row.DeletedAt.Valid {
+			return row, nil
+		}
+	}
+	return sql.Connection{}, pgx.ErrNoRows
+}
+
+func TestAuthorizeConnection(t *testing.T) {
+	owner := User{ID: 1, Email: "owner@example.com"}
+	store := fakeConnections{rows: []sql.Connection{
+		{ConnectionID: "issuer-conn", ID: 1, Role: config.RoleIssuer},
+		{ConnectionID: "holder-conn", ID: 1, Role: config.RoleHolder},
+		{ConnectionID: "archived-conn", ID: 1, Role: config.RoleIssuer, DeletedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}},
+		{ConnectionID: "other-conn", ID: 2, Role: config.RoleIssuer},
+	}}
+	dbErr := errors.New("connection refused")
+
+	tests := []struct {
+		name         string
+		store        ConnectionStore
+		user         User
+		role         string
+		connectionID string
+		wantErr      error
+		wantHTTP     int
+	}{
+		{name: "own connection", store: store, user: owner, role: config.RoleIssuer, connectionID: "issuer-conn"},
+		{name: "empty connection id", store: store, user: owner, role: config.RoleIssuer, connectionID: "", wantErr: ErrForbidden, wantHTTP: http.StatusForbidden},
+		{name: "unknown connection", store: store, user: owner, role: config.RoleIssuer, connectionID: "missing", wantErr: ErrForbidden, wantHTTP: http.StatusForbidden},
+		{name: "another user's connection", store: store, user: owner, role: config.RoleIssuer, connectionID: "other-conn", wantErr: ErrForbidden, wantHTTP: http.StatusForbidden},
+		{name: "connection of another role's agent", store: store, user: owner, role: config.RoleIssuer, connectionID: "holder-conn", wantErr: ErrForbidden, wantHTTP: http.StatusForbidden},
+		{name: "archived connection", store: store, user: owner, role: config.RoleIssuer, connectionID: "archived-conn", wantErr: ErrForbidden, wantHTTP: http.StatusForbidden},
+		{name: "store failure", store: fakeConnections{err: dbErr}, user: owner, role: config.RoleIssuer, connectionID: "issuer-conn", wantErr: dbErr, wantHTTP: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := AuthorizeConnection(context.Background(), tt.store, tt.user, tt.role, tt.connectionID)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("AuthorizeConnection error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil {
+				return
+			}
+			rec := httptest.NewRecorder()
+			WriteAuthorizationError(rec, err)
+			if rec.Code != tt.wantHTTP {
+				t.Errorf("WriteAuthorizationError status = %d, want %d", rec.Code, tt.wantHTTP)
+			}
+		})
+	}
+}
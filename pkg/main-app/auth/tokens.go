@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"digiauth/pkg/main-app/config"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AccessToken  = "access"
+	RefreshToken = "refresh"
+	// InvitationToken is carried by the magic links of invitation emails
+	InvitationToken = "invitation"
+)
+
+var ErrInvalidToken = errors.New("invalid or expired token")
+
+// Claims are the JWT claims of all token kinds. The subject is the user ID,
+// or the inviter's connection ID for invitation tokens, whose email is the
+// recipient's.
+type Claims struct {
+	Email string `json:"email"`
+	Type  string `json:"typ"`
+	jwt.RegisteredClaims
+}
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// TokenManager signs and verifies HS256 access, refresh and invitation tokens
+type TokenManager struct {
+	secret     []byte
+	issuer     string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewTokenManager(cfg config.Auth) *TokenManager {
+	return &TokenManager{
+		secret:     []byte(cfg.Secret),
+		issuer:     cfg.Issuer,
+		accessTTL:  time.Duration(cfg.AccessTTL),
+		refreshTTL: time.Duration(cfg.RefreshTTL),
+	}
+}
+
+// Issue returns a fresh access and refresh token for the user
+func (m *TokenManager) Issue(user User) (TokenPair, error) {
+	access, err := m.sign(user, AccessToken, m.accessTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	refresh, err := m.sign(user, RefreshToken, m.refreshTTL)
+	if err != nil {
+		return TokenPair{}, err
+	}
+	return TokenPair{
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(m.accessTTL.Seconds()),
+	}, nil
+}
+
+// Parse verifies a token of the given kind and returns the user it was issued to
+func (m *TokenManager) Parse(token, kind string) (User, error) {
+	claims, err := m.parse(token, kind)
+	if err != nil {
+		return User{}, err
+	}
+
+	id, err := strconv.ParseInt(claims.Subject, 10, 64)
+	if err != nil {
+		return User{}, ErrInvalidToken
+	}
+	return User{ID: id, Email: claims.Email}, nil
+}
+
+// IssueInvitation signs the magic link token for the invitation of the
+// inviter's connectionID sent to email. It expires with the invitation.
+func (m *TokenManager) IssueInvitation(connectionID, email string, expiresAt time.Time) (string, error) {
+	claims := Claims{
+		Email: email,
+		Type:  InvitationToken,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer,
+			Subject:   connectionID,
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
+
+// ParseInvitation verifies a magic link token and returns the inviter's
+// connection ID and the email the invitation was sent to
+func (m *TokenManager) ParseInvitation(token string) (string, string, error) {
+	claims, err := m.parse(token, InvitationToken)
+	if err != nil {
+		return "", "", err
+	}
+	if claims.Subject == "" {
+		return "", "", ErrInvalidToken
+	}
+	return claims.Subject, claims.Email, nil
+}
+
+func (m *TokenManager) parse(token, kind string) (Claims, error) {
+	var claims Claims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (interface{}, error) {
+		return m.secret, nil
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(m.issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil || claims.Type != kind {
+		return Claims{}, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+func (m *TokenManager) sign(user User, kind string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := Claims{
+		Email: user.Email,
+		Type:  kind,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    m.issuer,
+			Subject:   strconv.FormatInt(user.ID, 10),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(m.secret)
+}
@@ -0,0 +1,404 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+)
+
+// Roles name the three servers. They tag the rows written on behalf of each agent.
+const (
+	RoleIssuer   = "issuer"
+	RoleHolder   = "holder"
+	RoleVerifier = "verifier"
+)
+
+// Agents holds the admin API base URLs of the ACA-Py agents used by each role.
+// Each role server does everything on its own agent, invitations it creates
+// or receives included, since the connections they make are the ones it
+// later issues, presents or verifies over. InvitationProtocol is
+// "out-of-band", or "connections" for older agents.
+type Agents struct {
+	Issuer             string `json:"issuer"`
+	Holder             string `json:"holder"`
+	Verifier           string `json:"verifier"`
+	InvitationProtocol string `json:"invitation_protocol"`
+}
+
+// Database holds the postgres connection settings and the pool limits
+type Database struct {
+	User              string   `json:"user"`
+	Password          string   `json:"password"`
+	Host              string   `json:"host"`
+	Port              string   `json:"port"`
+	Name              string   `json:"name"`
+	MaxConns          int32    `json:"max_conns"`
+	MinConns          int32    `json:"min_conns"`
+	HealthCheckPeriod Duration `json:"health_check_period"`
+	MigrateOnStartup  bool     `json:"migrate_on_startup"`
+}
+
+// Auth holds the settings used to sign and verify access and refresh tokens
+type Auth struct {
+	Secret     string   `json:"secret"`
+	Issuer     string   `json:"issuer"`
+	AccessTTL  Duration `json:"access_ttl"`
+	RefreshTTL Duration `json:"refresh_ttl"`
+}
+
+// Webhooks configures the endpoints the agents post their events to. Agents
+// must be started with --webhook-url <url>#<api key>, posts without the key
+// are rejected.
+type Webhooks struct {
+	APIKey string `json:"api_key"`
+}
+
+// Notifier selects how emails are delivered: "http" posts them to an email
+// service, "smtp" sends them through a mail server and "log" writes them to a
+// file or the log for development
+type Notifier struct {
+	Driver string       `json:"driver"`
+	HTTP   HTTPNotifier `json:"http"`
+	SMTP   SMTP         `json:"smtp"`
+	Log    LogNotifier  `json:"log"`
+}
+
+type HTTPNotifier struct {
+	URL string `json:"url"`
+}
+
+type SMTP struct {
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	From     string `json:"from"`
+}
+
+// LogNotifier appends messages to Path, or writes them to the log when empty
+type LogNotifier struct {
+	Path string `json:"path"`
+}
+
+// Emails configures how emails are rendered. Templates in TemplateDir
+// replace the built-in ones of the same name and AcceptURL is the frontend
+// page invitation links point to.
+type Emails struct {
+	TemplateDir string `json:"template_dir"`
+	AcceptURL   string `json:"accept_url"`
+	Brand       Brand  `json:"brand"`
+}
+
+type Brand struct {
+	Name    string `json:"name"`
+	LogoURL string `json:"logo_url"`
+	Color   string `json:"color"`
+}
+
+// Invitations controls how long connection invitations stay usable
+type Invitations struct {
+	TTL Duration `json:"ttl"`
+}
+
+// Connections sets how often connection states are reconciled with the
+// agents and how often active connections are pinged. Connections not heard
+// from for StaleAfter are flagged stale. A zero interval disables that job.
+type Connections struct {
+	ReconcileInterval Duration `json:"reconcile_interval"`
+	LivenessInterval  Duration `json:"liveness_interval"`
+	StaleAfter        Duration `json:"stale_after"`
+}
+
+// CredentialExchanges configures the local records of credential exchanges.
+// With an EncryptionKey (32 bytes, base64 encoded) attribute values are
+// stored encrypted.
+type CredentialExchanges struct {
+	EncryptionKey string `json:"encryption_key"`
+}
+
+// Revocations sets how often pending credential revocations are published
+// to the ledger in one batch. A zero interval leaves them pending until
+// published on demand.
+type Revocations struct {
+	PublishInterval Duration `json:"publish_interval"`
+}
+
+type Config struct {
+	Agents              Agents              `json:"agents"`
+	Database            Database            `json:"database"`
+	Auth                Auth                `json:"auth"`
+	Webhooks            Webhooks            `json:"webhooks"`
+	Notifier            Notifier            `json:"notifier"`
+	Emails              Emails              `json:"emails"`
+	Invitations         Invitations         `json:"invitations"`
+	Connections         Connections         `json:"connections"`
+	CredentialExchanges CredentialExchanges `json:"credential_exchanges"`
+	Revocations         Revocations         `json:"revocations"`
+	LedgerURL           string              `json:"ledger_url"`
+}
+
+// Duration is a time.Duration read from strings such as "30s" or "5m"
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return err
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Default returns the configuration used for local development
+func Default() *Config {
+	return &Config{
+		Agents: Agents{
+			Issuer:             "http://localhost:8041",
+			Holder:             "http://localhost:6041",
+			Verifier:           "http://localhost:4041",
+			InvitationProtocol: "out-of-band",
+		},
+		Database: Database{
+			Host:              "localhost",
+			Port:              "5432",
+			MaxConns:          10,
+			MinConns:          1,
+			HealthCheckPeriod: Duration(time.Minute),
+			MigrateOnStartup:  true,
+		},
+		Auth: Auth{
+			Issuer:     "digiauth",
+			AccessTTL:  Duration(15 * time.Minute),
+			RefreshTTL: Duration(7 * 24 * time.Hour),
+		},
+		Notifier: Notifier{
+			Driver: "http",
+			HTTP:   HTTPNotifier{URL: "https://q648rhgza1.execute-api.ap-south-1.amazonaws.com/prod"},
+			SMTP:   SMTP{Port: "587"},
+		},
+		Emails: Emails{
+			AcceptURL: "http://localhost:3000/accept-invitation",
+			Brand:     Brand{Name: "DigiAuth", Color: "#1f4e79"},
+		},
+		Invitations: Invitations{
+			TTL: Duration(7 * 24 * time.Hour),
+		},
+		Connections: Connections{
+			ReconcileInterval: Duration(time.Minute),
+			LivenessInterval:  Duration(time.Hour),
+			StaleAfter:        Duration(24 * time.Hour),
+		},
+		Revocations: Revocations{
+			PublishInterval: Duration(15 * time.Minute),
+		},
+		LedgerURL: "http://test.bcovrin.vonx.io/register",
+	}
+}
+
+// Load builds the configuration from the defaults, then the JSON file at path
+// (if it exists) and finally the environment, each overriding the previous one
+func Load(path string) (*Config, error) {
+	// The .env file is optional, variables may also come from the environment
+	_ = godotenv.Load("./.env")
+
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(data, cfg); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	setFromEnv(&cfg.Agents.Issuer, "ISSUER_AGENT_URL")
+	setFromEnv(&cfg.Agents.Holder, "HOLDER_AGENT_URL")
+	setFromEnv(&cfg.Agents.Verifier, "VERIFIER_AGENT_URL")
+	setFromEnv(&cfg.Agents.InvitationProtocol, "INVITATION_PROTOCOL")
+	// Database variables keep the names used by the original .env files
+	setFromEnv(&cfg.Database.User, "user")
+	setFromEnv(&cfg.Database.Password, "password")
+	setFromEnv(&cfg.Database.Host, "host")
+	setFromEnv(&cfg.Database.Port, "port")
+	setFromEnv(&cfg.Database.Name, "dbname")
+	if err := setInt32FromEnv(&cfg.Database.MaxConns, "DB_MAX_CONNS"); err != nil {
+		return nil, err
+	}
+	if err := setInt32FromEnv(&cfg.Database.MinConns, "DB_MIN_CONNS"); err != nil {
+		return nil, err
+	}
+	if err := setDurationFromEnv(&cfg.Database.HealthCheckPeriod, "DB_HEALTH_CHECK_PERIOD"); err != nil {
+		return nil, err
+	}
+	if err := setBoolFromEnv(&cfg.Database.MigrateOnStartup, "DB_MIGRATE_ON_STARTUP"); err != nil {
+		return nil, err
+	}
+	setFromEnv(&cfg.Auth.Secret, "JWT_SECRET")
+	if err := setDurationFromEnv(&cfg.Auth.AccessTTL, "JWT_ACCESS_TTL"); err != nil {
+		return nil, err
+	}
+	if err := setDurationFromEnv(&cfg.Auth.RefreshTTL, "JWT_REFRESH_TTL"); err != nil {
+		return nil, err
+	}
+	setFromEnv(&cfg.Webhooks.APIKey, "WEBHOOK_API_KEY")
+	setFromEnv(&cfg.Notifier.Driver, "NOTIFIER_DRIVER")
+	setFromEnv(&cfg.Notifier.HTTP.URL, "EMAIL_URL")
+	setFromEnv(&cfg.Notifier.SMTP.Host, "SMTP_HOST")
+	setFromEnv(&cfg.Notifier.SMTP.Port, "SMTP_PORT")
+	setFromEnv(&cfg.Notifier.SMTP.Username, "SMTP_USERNAME")
+	setFromEnv(&cfg.Notifier.SMTP.Password, "SMTP_PASSWORD")
+	setFromEnv(&cfg.Notifier.SMTP.From, "SMTP_FROM")
+	setFromEnv(&cfg.Notifier.Log.Path, "NOTIFIER_LOG_PATH")
+	setFromEnv(&cfg.Emails.TemplateDir, "EMAIL_TEMPLATE_DIR")
+	setFromEnv(&cfg.Emails.AcceptURL, "EMAIL_ACCEPT_URL")
+	setFromEnv(&cfg.Emails.Brand.Name, "BRAND_NAME")
+	setFromEnv(&cfg.Emails.Brand.LogoURL, "BRAND_LOGO_URL")
+	setFromEnv(&cfg.Emails.Brand.Color, "BRAND_COLOR")
+	if err := setDurationFromEnv(&cfg.Invitations.TTL, "INVITATION_TTL"); err != nil {
+		return nil, err
+	}
+	if err := setDurationFromEnv(&cfg.Connections.ReconcileInterval, "CONNECTION_RECONCILE_INTERVAL"); err != nil {
+		return nil, err
+	}
+	if err := setDurationFromEnv(&cfg.Connections.LivenessInterval, "CONNECTION_LIVENESS_INTERVAL"); err != nil {
+		return nil, err
+	}
+	if err := setDurationFromEnv(&cfg.Connections.StaleAfter, "CONNECTION_STALE_AFTER"); err != nil {
+		return nil, err
+	}
+	setFromEnv(&cfg.CredentialExchanges.EncryptionKey, "CREDENTIAL_ENCRYPTION_KEY")
+	if err := setDurationFromEnv(&cfg.Revocations.PublishInterval, "REVOCATION_PUBLISH_INTERVAL"); err != nil {
+		return nil, err
+	}
+	setFromEnv(&cfg.LedgerURL, "LEDGER_URL")
+
+	cfg.Agents.Issuer = strings.TrimRight(cfg.Agents.Issuer, "/")
+	cfg.Agents.Holder = strings.TrimRight(cfg.Agents.Holder, "/")
+	cfg.Agents.Verifier = strings.TrimRight(cfg.Agents.Verifier, "/")
+
+	return cfg, cfg.Validate()
+}
+
+func (c *Config) Validate() error {
+	if c.Agents.Issuer == "" || c.Agents.Holder == "" || c.Agents.Verifier == "" {
+		return errors.New("config: issuer, holder and verifier agent URLs are required")
+	}
+	if c.Agents.InvitationProtocol != "out-of-band" && c.Agents.InvitationProtocol != "connections" {
+		return errors.New("config: agents invitation_protocol must be out-of-band or connections")
+	}
+	if c.Database.Name == "" {
+		return errors.New("config: database name is required")
+	}
+	if c.Database.MaxConns < 1 || c.Database.MinConns < 0 || c.Database.MinConns > c.Database.MaxConns {
+		return errors.New("config: database pool needs 0 <= min_conns <= max_conns and max_conns >= 1")
+	}
+	if len(c.Auth.Secret) < 32 {
+		return errors.New("config: auth secret must be at least 32 characters")
+	}
+	if c.Auth.AccessTTL <= 0 || c.Auth.RefreshTTL <= 0 {
+		return errors.New("config: auth access_ttl and refresh_ttl must be positive")
+	}
+	if len(c.Webhooks.APIKey) < 16 {
+		return errors.New("config: webhooks api_key must be at least 16 characters")
+	}
+	if c.LedgerURL == "" {
+		return errors.New("config: ledger_url is required")
+	}
+	switch c.Notifier.Driver {
+	case "http":
+		if c.Notifier.HTTP.URL == "" {
+			return errors.New("config: notifier http.url is required")
+		}
+	case "smtp":
+		if c.Notifier.SMTP.Host == "" || c.Notifier.SMTP.Port == "" || c.Notifier.SMTP.From == "" {
+			return errors.New("config: notifier smtp host, port and from are required")
+		}
+	case "log":
+	default:
+		return errors.New("config: notifier driver must be http, smtp or log")
+	}
+	if c.Emails.AcceptURL == "" || c.Emails.Brand.Name == "" {
+		return errors.New("config: emails accept_url and brand name are required")
+	}
+	if c.Invitations.TTL <= 0 {
+		return errors.New("config: invitations ttl must be positive")
+	}
+	if c.Connections.ReconcileInterval < 0 || c.Connections.LivenessInterval < 0 {
+		return errors.New("config: connections reconcile_interval and liveness_interval must not be negative")
+	}
+	if c.Connections.StaleAfter <= 0 {
+		return errors.New("config: connections stale_after must be positive")
+	}
+	if key := c.CredentialExchanges.EncryptionKey; key != "" {
+		if raw, err := base64.StdEncoding.DecodeString(key); err != nil || len(raw) != 32 {
+			return errors.New("config: credential_exchanges encryption_key must be 32 bytes, base64 encoded")
+		}
+	}
+	if c.Revocations.PublishInterval < 0 {
+		return errors.New("config: revocations publish_interval must not be negative")
+	}
+	return nil
+}
+
+func setFromEnv(dst *string, key string) {
+	if v, ok := os.LookupEnv(key); ok && v != "" {
+		*dst = v
+	}
+}
+
+func setInt32FromEnv(dst *int32, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	n, err := strconv.ParseInt(v, 10, 32)
+	if err != nil {
+		return errors.New("config: " + key + " must be an integer")
+	}
+	*dst = int32(n)
+	return nil
+}
+
+func setDurationFromEnv(dst *Duration, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return errors.New("config: " + key + " must be a duration such as 30s")
+	}
+	*dst = Duration(d)
+	return nil
+}
+
+func setBoolFromEnv(dst *bool, key string) error {
+	v, ok := os.LookupEnv(key)
+	if !ok || v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return errors.New("config: " + key + " must be true or false")
+	}
+	*dst = b
+	return nil
+}
@@ -0,0 +1,110 @@
+package connections
+
+import (
+	"context"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/db"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"digiauth/pkg/main-app/server"
+	"log"
+	"time"
+)
+
+// Connection states as reported by the agents
+const (
+	StateInvitation = "invitation"
+	StateRequest    = "request"
+	StateResponse   = "response"
+	StateActive     = "active"
+	StateCompleted  = "completed"
+	StateError      = "error"
+	StateAbandoned  = "abandoned"
+	StateDeleted    = "deleted"
+)
+
+// ValidState reports whether state is one connections can be filtered by
+func ValidState(state string) bool {
+	switch state {
+	case StateInvitation, StateRequest, StateResponse, StateActive, StateCompleted, StateError, StateAbandoned, StateDeleted:
+		return true
+	}
+	return false
+}
+
+// Reconciler copies the state the agent has for each connection into the
+// store. Webhooks keep rows current, the reconciler catches up on the ones
+// missed while the server was down or delivered before the row was written.
+type Reconciler struct {
+	role     string
+	agent    acapy.Agent
+	store    *db.Store
+	interval time.Duration
+}
+
+func NewReconciler(deps server.Dependencies) *Reconciler {
+	return &Reconciler{
+		role:     deps.Role,
+		agent:    deps.Agent,
+		store:    deps.Store,
+		interval: time.Duration(deps.Config.Connections.ReconcileInterval),
+	}
+}
+
+// Run reconciles once and then on every interval until ctx is done. It
+// returns straight away when the interval is zero.
+func (r *Reconciler) Run(ctx context.Context) {
+	every(ctx, r.interval, func() {
+		if err := r.Reconcile(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error reconciling %s connections : %v", r.role, err)
+		}
+	})
+}
+
+// Reconcile updates every stored connection whose state, label or DID
+// differs from the agent's record. Records the store does not know are
+// left alone.
+func (r *Reconciler) Reconcile(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	records, err := r.agent.ListConnections(ctx)
+	if err != nil {
+		return err
+	}
+	var updated int64
+	for _, record := range records {
+		n, err := r.store.UpdateConnectionState(ctx, sql.UpdateConnectionStateParams{
+			State:        record.State,
+			TheirLabel:   record.TheirLabel,
+			TheirDid:     record.TheirDID,
+			ConnectionID: record.ConnectionID,
+			Role:         r.role,
+		})
+		if err != nil {
+			return err
+		}
+		updated += n
+	}
+	if updated > 0 {
+		log.Printf("Reconciled %d %s connections with the agent", updated, r.role)
+	}
+	return nil
+}
+
+// every calls job straight away and then on every interval until ctx is
+// done. A zero interval disables the job.
+func every(ctx context.Context, interval time.Duration, job func()) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		job()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
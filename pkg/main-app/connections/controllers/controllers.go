@@ -0,0 +1,201 @@
+package connections
+
+import (
+	"context"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/auth"
+	liveness "digiauth/pkg/main-app/connections"
+	models "digiauth/pkg/main-app/connections/models"
+	"digiauth/pkg/main-app/db"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"digiauth/pkg/main-app/server"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// Limits on what users may store on a connection
+const (
+	maxAliasLength  = 100
+	maxMetadataKeys = 50
+)
+
+// aliasMetadataKey holds the alias in the agent's copy of the metadata
+const aliasMetadataKey = "alias"
+
+// Controller manages the caller's connections on this server's agent
+type Controller struct {
+	role  string
+	agent acapy.Agent
+	store *db.Store
+}
+
+func NewController(deps server.Dependencies) *Controller {
+	return &Controller{role: deps.Role, agent: deps.Agent, store: deps.Store}
+}
+
+// DeleteConnection removes the connection from the agent and archives the
+// local row, which is kept for audit and hidden from listings
+func (c *Controller) DeleteConnection(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	connectionID := mux.Vars(r)["connection_id"]
+	user, _ := auth.UserFromContext(r.Context())
+	if err := auth.AuthorizeConnection(ctx, c.store, user, c.role, connectionID); err != nil {
+		auth.WriteAuthorizationError(w, err)
+		return
+	}
+
+	// The row is known to belong to this server's agent, so a record the
+	// agent no longer has was deleted there already and archiving the row is
+	// all that is left to do
+	if err := c.agent.DeleteConnection(ctx, connectionID); err != nil && !acapy.IsNotFound(err) {
+		log.Println("Failed to delete connection: ", err)
+		http.Error(w, "Failed to delete connection: "+err.Error(), acapy.StatusCode(err))
+		return
+	}
+
+	archived, err := c.store.ArchiveConnection(ctx, sql.ArchiveConnectionParams{
+		ConnectionID: connectionID,
+		ID:           user.ID,
+		Role:         c.role,
+	})
+	if err != nil {
+		log.Println("Error archiving connection : ", err.Error())
+		http.Error(w, "Error archiving connection : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if archived == 0 {
+		http.Error(w, "Connection was already deleted", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "Connection Deleted"}`))
+}
+
+// UpdateConnection sets the alias and metadata of a connection. The metadata
+// replaces the stored one and is mirrored to the agent along with the alias,
+// keys that were removed are cleared there.
+func (c *Controller) UpdateConnection(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var requestData models.UpdateConnectionRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	requestData.Alias = strings.TrimSpace(requestData.Alias)
+	if len(requestData.Alias) > maxAliasLength {
+		http.Error(w, "alias must be at most 100 characters", http.StatusBadRequest)
+		return
+	}
+	if len(requestData.Metadata) > maxMetadataKeys {
+		http.Error(w, "metadata may have at most 50 keys", http.StatusBadRequest)
+		return
+	}
+	for key := range requestData.Metadata {
+		if key == "" || key == aliasMetadataKey {
+			http.Error(w, "metadata keys must be non-empty and not \"alias\"", http.StatusBadRequest)
+			return
+		}
+	}
+	if requestData.Metadata == nil {
+		requestData.Metadata = map[string]interface{}{}
+	}
+
+	connectionID := mux.Vars(r)["connection_id"]
+	user, _ := auth.UserFromContext(r.Context())
+	if err := auth.AuthorizeConnection(ctx, c.store, user, c.role, connectionID); err != nil {
+		auth.WriteAuthorizationError(w, err)
+		return
+	}
+	connection, err := c.store.GetConnectionByID(ctx, connectionID)
+	if err != nil {
+		log.Println("Error fetching connection from db : ", err.Error())
+		http.Error(w, "Error fetching connection from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if connection.DeletedAt.Valid {
+		http.Error(w, "Connection was deleted", http.StatusNotFound)
+		return
+	}
+
+	var previous map[string]interface{}
+	if err := json.Unmarshal(connection.Metadata, &previous); err != nil {
+		log.Println("Error decoding stored connection metadata : ", err.Error())
+	}
+	mirrored := map[string]interface{}{aliasMetadataKey: requestData.Alias}
+	for key := range previous {
+		mirrored[key] = nil
+	}
+	for key, value := range requestData.Metadata {
+		mirrored[key] = value
+	}
+	if err := c.agent.SetConnectionMetadata(ctx, connectionID, mirrored); err != nil {
+		log.Println("Failed to set connection metadata: ", err)
+		http.Error(w, "Failed to set connection metadata: "+err.Error(), acapy.StatusCode(err))
+		return
+	}
+
+	metadata, err := json.Marshal(requestData.Metadata)
+	if err != nil {
+		http.Error(w, "Invalid metadata", http.StatusBadRequest)
+		return
+	}
+	updated, err := c.store.UpdateConnectionDetails(ctx, sql.UpdateConnectionDetailsParams{
+		Alias:        requestData.Alias,
+		Metadata:     metadata,
+		ConnectionID: connectionID,
+		ID:           user.ID,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Connection was deleted", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Error updating connection : ", err.Error())
+		http.Error(w, "Error updating connection : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(updated)
+}
+
+// PingConnection sends a trust ping to check the other agent is reachable.
+// It answers once the ping is sent, the connection's last-seen time moves
+// when the answer comes back on the ping webhook.
+func (c *Controller) PingConnection(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	connectionID := mux.Vars(r)["connection_id"]
+	user, _ := auth.UserFromContext(r.Context())
+	if err := auth.AuthorizeConnection(ctx, c.store, user, c.role, connectionID); err != nil {
+		auth.WriteAuthorizationError(w, err)
+		return
+	}
+
+	result, err := liveness.Ping(ctx, c.agent, c.store, connectionID)
+	if err != nil {
+		log.Println("Failed to send trust ping: ", err)
+		http.Error(w, "Failed to send trust ping: "+err.Error(), acapy.StatusCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Trust Ping Sent",
+		"thread_id": result.ThreadID,
+	})
+}
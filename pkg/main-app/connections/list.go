@@ -0,0 +1,114 @@
+package connections
+
+import (
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Page sizes of connection listings
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ListParams reads the query string of a connection listing:
+//
+//	state             only connections in this agent state
+//	q                 alias, counterparty email or label containing this text
+//	include_archived  "true" to list deleted connections too
+//	order             "desc" (newest first, the default) or "asc" by creation time
+//	limit             page size, up to MaxLimit
+//	cursor            next_cursor of the previous page
+//
+// One row more than the page size is asked for so that Page can tell whether
+// there is a next page.
+func ListParams(r *http.Request, userID int64, role string) (sql.ListConnectionsParams, int, error) {
+	query := r.URL.Query()
+	params := sql.ListConnectionsParams{UserID: userID, Role: role}
+
+	params.State = query.Get("state")
+	if params.State != "" && !ValidState(params.State) {
+		return params, 0, errors.New("unknown connection state: " + params.State)
+	}
+	params.Search = escapeLike(strings.TrimSpace(query.Get("q")))
+	if v := query.Get("include_archived"); v != "" {
+		include, err := strconv.ParseBool(v)
+		if err != nil {
+			return params, 0, errors.New("include_archived must be true or false")
+		}
+		params.IncludeArchived = include
+	}
+
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		params.Ascending = true
+	default:
+		return params, 0, errors.New("order must be asc or desc")
+	}
+
+	limit := DefaultLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > MaxLimit {
+			return params, 0, errors.New("limit must be between 1 and " + strconv.Itoa(MaxLimit))
+		}
+		limit = n
+	}
+	params.RowLimit = int32(limit + 1)
+
+	if v := query.Get("cursor"); v != "" {
+		createdAt, connectionID, err := decodeCursor(v)
+		if err != nil {
+			return params, 0, err
+		}
+		params.CursorCreatedAt = pgtype.Timestamptz{Time: createdAt, Valid: true}
+		params.CursorConnectionID = connectionID
+	}
+	return params, limit, nil
+}
+
+// Page trims the extra row ListParams asked for and returns the cursor of the
+// next page, or "" on the last one
+func Page(rows []sql.Connection, limit int) ([]sql.Connection, string) {
+	if len(rows) <= limit {
+		return rows, ""
+	}
+	rows = rows[:limit]
+	last := rows[len(rows)-1]
+	return rows, encodeCursor(last.CreatedAt.Time, last.ConnectionID)
+}
+
+func encodeCursor(createdAt time.Time, connectionID string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(createdAt.UTC().Format(time.RFC3339Nano) + "|" + connectionID))
+}
+
+func decodeCursor(cursor string) (time.Time, string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	createdAt, connectionID, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return time.Time{}, "", ErrInvalidCursor
+	}
+	return t, connectionID, nil
+}
+
+// escapeLike makes the search text match literally inside ILIKE patterns
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
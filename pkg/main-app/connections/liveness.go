@@ -0,0 +1,96 @@
+package connections
+
+import (
+	"context"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/db"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"digiauth/pkg/main-app/server"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// PingComment is sent with every trust ping so the other party can tell
+// what it is for
+const PingComment = "DigiAuth liveness check"
+
+// Sweeper pings the active connections stored for the server's agent and
+// flags as stale the ones whose other agent has not been heard from for a
+// while. Answers arrive on the ping webhook, which needs the agent started
+// with --monitor-ping.
+type Sweeper struct {
+	role       string
+	agent      acapy.Agent
+	store      *db.Store
+	interval   time.Duration
+	staleAfter time.Duration
+}
+
+func NewSweeper(deps server.Dependencies) *Sweeper {
+	return &Sweeper{
+		role:       deps.Role,
+		agent:      deps.Agent,
+		store:      deps.Store,
+		interval:   time.Duration(deps.Config.Connections.LivenessInterval),
+		staleAfter: time.Duration(deps.Config.Connections.StaleAfter),
+	}
+}
+
+// Run sweeps once and then on every interval until ctx is done. It returns
+// straight away when the interval is zero.
+func (s *Sweeper) Run(ctx context.Context) {
+	every(ctx, s.interval, func() {
+		if err := s.Sweep(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error checking liveness of %s connections : %v", s.role, err)
+		}
+	})
+}
+
+// Sweep flags stale connections, then pings every active one. The answers
+// to this sweep's pings count towards the next one. A sweep is cut short
+// after one interval so a hung agent call cannot hold up the next one.
+func (s *Sweeper) Sweep(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.interval)
+	defer cancel()
+
+	// Connections on the agent that DigiAuth does not track are left alone
+	active, err := s.store.ListLiveConnectionIDs(ctx, s.role)
+	if err != nil {
+		return err
+	}
+	if len(active) == 0 {
+		return nil
+	}
+
+	flagged, err := s.store.FlagStaleConnections(ctx, sql.FlagStaleConnectionsParams{
+		Cutoff:        pgtype.Timestamptz{Time: time.Now().Add(-s.staleAfter), Valid: true},
+		ConnectionIds: active,
+	})
+	if err != nil {
+		return err
+	}
+	if flagged > 0 {
+		log.Printf("Updated the stale flag of %d %s connections", flagged, s.role)
+	}
+
+	for _, connectionID := range active {
+		if _, err := Ping(ctx, s.agent, s.store, connectionID); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Printf("Failed to ping %s connection %s : %v", s.role, connectionID, err)
+		}
+	}
+	return nil
+}
+
+// Ping sends a trust ping over the connection and records when it was sent
+func Ping(ctx context.Context, agent acapy.Agent, store *db.Store, connectionID string) (acapy.PingResult, error) {
+	result, err := agent.SendPing(ctx, connectionID, acapy.PingRequest{Comment: PingComment})
+	if err != nil {
+		return result, err
+	}
+	return result, store.RecordConnectionPing(ctx, connectionID)
+}
@@ -0,0 +1,7 @@
+package connections
+
+// UpdateConnectionRequest replaces a connection's alias and metadata
+type UpdateConnectionRequest struct {
+	Alias    string                 `json:"alias"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
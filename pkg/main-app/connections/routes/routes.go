@@ -0,0 +1,17 @@
+package connections
+
+import (
+	controllers "digiauth/pkg/main-app/connections/controllers"
+	"digiauth/pkg/main-app/server"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes adds the connection management endpoints to a role's
+// protected router
+func RegisterRoutes(protected *mux.Router, deps server.Dependencies) {
+	controller := controllers.NewController(deps)
+	protected.HandleFunc("/connections/{connection_id}", controller.UpdateConnection).Methods("PUT")
+	protected.HandleFunc("/connections/{connection_id}", controller.DeleteConnection).Methods("DELETE")
+	protected.HandleFunc("/connections/{connection_id}/ping", controller.PingConnection).Methods("POST")
+}
@@ -2,45 +2,69 @@ package db
 
 import (
 	"context"
-	"log"
-	"os"
+	"digiauth/pkg/main-app/config"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"fmt"
+	"net/url"
+	"time"
 
-	"github.com/jackc/pgx/v5"
-	"github.com/joho/godotenv"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-var DB *pgx.Conn
+// Store is the repository shared by the Issuer, Receiver and Verifier servers.
+// It embeds the generated queries and runs them on a connection pool, so it is
+// safe for concurrent use by all handlers.
+type Store struct {
+	*sql.Queries
+	pool *pgxpool.Pool
+}
 
-func InitDB() error {
+// NewStore opens the connection pool described by cfg and checks that the
+// database is reachable
+func NewStore(ctx context.Context, cfg config.Database) (*Store, error) {
+	poolConfig, err := pgxpool.ParseConfig(connString(cfg))
+	if err != nil {
+		return nil, fmt.Errorf("parse database config: %w", err)
+	}
+	poolConfig.MaxConns = cfg.MaxConns
+	poolConfig.MinConns = cfg.MinConns
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = time.Duration(cfg.HealthCheckPeriod)
+	}
 
-	// Load .env file
-	err := godotenv.Load("./.env")
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
 	if err != nil {
-		log.Fatalf("Error loading .env file")
-		return err
+		return nil, fmt.Errorf("unable to create connection pool: %w", err)
 	}
 
-	// Read environment variables
-	user := os.Getenv("user")
-	password := os.Getenv("password")
-	host := os.Getenv("host")
-	port := os.Getenv("port")
-	dbname := os.Getenv("dbname")
+	if err := pool.Ping(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("unable to connect to database: %w", err)
+	}
 
-	connString := "postgres://" + user + ":" + password + "@" + host + ":" + port + "/" + dbname
+	return &Store{Queries: sql.New(pool), pool: pool}, nil
+}
 
-	// Connect to the database
-	DB, err = pgx.Connect(context.Background(), connString)
-	if err != nil {
-		log.Fatalf("Unable to connect to database: %v", err)
-		return err
-	}
+// Pool exposes the underlying pool for transactions and migrations
+func (s *Store) Pool() *pgxpool.Pool {
+	return s.pool
+}
+
+// Ping reports whether a connection can be acquired and used
+func (s *Store) Ping(ctx context.Context) error {
+	return s.pool.Ping(ctx)
+}
 
-	return nil
+func (s *Store) Close() {
+	s.pool.Close()
 }
 
-func CloseDB() {
-	if err := DB.Close(context.Background()); err != nil {
-		log.Fatalf("Error closing database connection: %v", err)
+func connString(cfg config.Database) string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(cfg.User, cfg.Password),
+		Host:   cfg.Host + ":" + cfg.Port,
+		Path:   "/" + cfg.Name,
 	}
+	return u.String()
 }
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS schemas;
+DROP TABLE IF EXISTS connections;
+DROP TABLE IF EXISTS users;
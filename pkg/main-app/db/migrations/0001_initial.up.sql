@@ -0,0 +1,35 @@
+-- Initial schema. Statements are idempotent so databases created from the
+-- old schema.sql can be brought under version control.
+
+-- role_enum was never used by any table
+DROP TYPE IF EXISTS role_enum;
+
+CREATE TABLE IF NOT EXISTS users (
+    id BIGSERIAL NOT NULL,
+    email TEXT NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (id),
+    UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS connections (
+    connection_id VARCHAR NOT NULL,
+    id BIGINT NOT NULL,
+    my_mail_id TEXT,
+    their_mail_id TEXT,
+    PRIMARY KEY (connection_id),
+    FOREIGN KEY (id) REFERENCES users(id)
+);
+
+-- connections.id references users(id), it must not draw values from its own sequence
+ALTER TABLE connections ALTER COLUMN id DROP DEFAULT;
+DROP SEQUENCE IF EXISTS connections_id_seq;
+
+CREATE TABLE IF NOT EXISTS schemas (
+    schema_id VARCHAR NOT NULL,
+    credential_definition_id VARCHAR NOT NULL,
+    schema_name VARCHAR NOT NULL,
+    attributes TEXT[],
+    PRIMARY KEY (schema_id)
+);
@@ -0,0 +1,7 @@
+-- Accounts log in with their email and a bcrypt password hash. Rows created
+-- before this migration have no password and cannot log in until one is set.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+UPDATE users SET email = lower(email) WHERE email <> lower(email);
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_idx ON users (lower(email));
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS revocation_registry_events;
+DROP TABLE IF EXISTS presentation_exchange_events;
+DROP TABLE IF EXISTS credential_exchange_events;
+DROP TABLE IF EXISTS connection_events;
@@ -0,0 +1,53 @@
+-- State transitions reported by the agents through their webhooks. Each row
+-- is one webhook delivery; role is the server that received it.
+
+CREATE TABLE IF NOT EXISTS connection_events (
+    id BIGSERIAL NOT NULL,
+    role TEXT NOT NULL,
+    connection_id VARCHAR NOT NULL,
+    state TEXT NOT NULL DEFAULT '',
+    rfc23_state TEXT NOT NULL DEFAULT '',
+    their_label TEXT NOT NULL DEFAULT '',
+    their_did TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS connection_events_connection_id_idx ON connection_events (connection_id, created_at);
+
+CREATE TABLE IF NOT EXISTS credential_exchange_events (
+    id BIGSERIAL NOT NULL,
+    role TEXT NOT NULL,
+    cred_ex_id VARCHAR NOT NULL,
+    connection_id VARCHAR NOT NULL DEFAULT '',
+    thread_id TEXT NOT NULL DEFAULT '',
+    state TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS credential_exchange_events_cred_ex_id_idx ON credential_exchange_events (cred_ex_id, created_at);
+CREATE INDEX IF NOT EXISTS credential_exchange_events_connection_id_idx ON credential_exchange_events (connection_id);
+
+CREATE TABLE IF NOT EXISTS presentation_exchange_events (
+    id BIGSERIAL NOT NULL,
+    role TEXT NOT NULL,
+    pres_ex_id VARCHAR NOT NULL,
+    connection_id VARCHAR NOT NULL DEFAULT '',
+    thread_id TEXT NOT NULL DEFAULT '',
+    state TEXT NOT NULL DEFAULT '',
+    verified TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS presentation_exchange_events_pres_ex_id_idx ON presentation_exchange_events (pres_ex_id, created_at);
+CREATE INDEX IF NOT EXISTS presentation_exchange_events_connection_id_idx ON presentation_exchange_events (connection_id);
+
+CREATE TABLE IF NOT EXISTS revocation_registry_events (
+    id BIGSERIAL NOT NULL,
+    role TEXT NOT NULL,
+    rev_reg_id VARCHAR NOT NULL,
+    cred_def_id VARCHAR NOT NULL DEFAULT '',
+    state TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS revocation_registry_events_rev_reg_id_idx ON revocation_registry_events (rev_reg_id, created_at);
@@ -0,0 +1,11 @@
+-- The invitation each connection was created from, kept so it can be shown
+-- again, e.g. as a QR code. role is the server whose agent created it.
+
+CREATE TABLE IF NOT EXISTS invitations (
+    connection_id VARCHAR NOT NULL,
+    role TEXT NOT NULL,
+    invitation JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (connection_id),
+    FOREIGN KEY (connection_id) REFERENCES connections(connection_id) ON DELETE CASCADE
+);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS invitations_user_id_idx;
+DROP INDEX IF EXISTS invitations_invitation_id_idx;
+ALTER TABLE invitations DROP CONSTRAINT IF EXISTS invitations_user_id_fkey;
+ALTER TABLE invitations DROP COLUMN IF EXISTS revoked_at;
+ALTER TABLE invitations DROP COLUMN IF EXISTS accepted_at;
+ALTER TABLE invitations DROP COLUMN IF EXISTS expires_at;
+ALTER TABLE invitations DROP COLUMN IF EXISTS status;
+ALTER TABLE invitations DROP COLUMN IF EXISTS recipient_email;
+ALTER TABLE invitations DROP COLUMN IF EXISTS user_id;
+ALTER TABLE invitations DROP COLUMN IF EXISTS invitation_id;
@@ -0,0 +1,26 @@
+-- Track who created each invitation, for whom, and whether it is still
+-- usable. status is pending, accepted or revoked; a pending invitation past
+-- expires_at is expired.
+
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS invitation_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS user_id BIGINT;
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS recipient_email TEXT NOT NULL DEFAULT '';
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'pending';
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS accepted_at TIMESTAMPTZ;
+ALTER TABLE invitations ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ;
+
+UPDATE invitations i
+SET invitation_id = i.invitation->>'@id',
+    user_id = c.id,
+    recipient_email = COALESCE(c.their_mail_id, ''),
+    expires_at = i.created_at + INTERVAL '7 days'
+FROM connections c
+WHERE c.connection_id = i.connection_id;
+
+ALTER TABLE invitations ALTER COLUMN user_id SET NOT NULL;
+ALTER TABLE invitations ALTER COLUMN expires_at SET NOT NULL;
+ALTER TABLE invitations ADD CONSTRAINT invitations_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS invitations_invitation_id_idx ON invitations (invitation_id);
+CREATE INDEX IF NOT EXISTS invitations_user_id_idx ON invitations (user_id, role, created_at);
@@ -0,0 +1,22 @@
+-- Multi-use invitations published by a user, e.g. an issuer on its website.
+-- connection_id is the agent's template record; the connections wallets make
+-- with it share invitation_msg_id or invitation_key and are attributed to
+-- user_id.
+
+CREATE TABLE IF NOT EXISTS public_invitations (
+    id TEXT NOT NULL,
+    user_id BIGINT NOT NULL,
+    role TEXT NOT NULL,
+    label TEXT NOT NULL DEFAULT '',
+    connection_id VARCHAR NOT NULL,
+    invitation JSONB NOT NULL,
+    invitation_msg_id TEXT NOT NULL DEFAULT '',
+    invitation_key TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    revoked_at TIMESTAMPTZ,
+    PRIMARY KEY (id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS public_invitations_user_id_idx ON public_invitations (user_id, role, created_at);
+CREATE INDEX IF NOT EXISTS public_invitations_invitation_msg_id_idx ON public_invitations (invitation_msg_id);
+CREATE INDEX IF NOT EXISTS public_invitations_invitation_key_idx ON public_invitations (invitation_key);
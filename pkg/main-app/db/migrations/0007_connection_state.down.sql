@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS connections_id_state_idx;
+ALTER TABLE connections DROP COLUMN IF EXISTS updated_at;
+ALTER TABLE connections DROP COLUMN IF EXISTS created_at;
+ALTER TABLE connections DROP COLUMN IF EXISTS their_did;
+ALTER TABLE connections DROP COLUMN IF EXISTS their_label;
+ALTER TABLE connections DROP COLUMN IF EXISTS state;
@@ -0,0 +1,28 @@
+-- Connections mirror the state the agent has for them. Rows are written when
+-- the invitation is created, so they start in the invitation state and are
+-- moved along by webhooks and the periodic reconciler.
+
+ALTER TABLE connections ADD COLUMN IF NOT EXISTS state TEXT NOT NULL DEFAULT 'invitation';
+ALTER TABLE connections ADD COLUMN IF NOT EXISTS their_label TEXT NOT NULL DEFAULT '';
+ALTER TABLE connections ADD COLUMN IF NOT EXISTS their_did TEXT NOT NULL DEFAULT '';
+ALTER TABLE connections ADD COLUMN IF NOT EXISTS created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+ALTER TABLE connections ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+-- Existing rows take the last state their webhooks reported
+UPDATE connections c
+SET state = e.state, their_label = e.their_label, their_did = e.their_did, updated_at = e.created_at
+FROM (
+    SELECT DISTINCT ON (connection_id) connection_id, state, their_label, their_did, created_at
+    FROM connection_events
+    WHERE state <> ''
+    ORDER BY connection_id, created_at DESC, id DESC
+) e
+WHERE c.connection_id = e.connection_id;
+
+-- Invitations record when their connection was created
+UPDATE connections c
+SET created_at = i.created_at
+FROM invitations i
+WHERE c.connection_id = i.connection_id;
+
+CREATE INDEX IF NOT EXISTS connections_id_state_idx ON connections (id, state);
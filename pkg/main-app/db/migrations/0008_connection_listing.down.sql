@@ -0,0 +1 @@
+DROP INDEX IF EXISTS connections_id_created_at_idx;
@@ -0,0 +1,2 @@
+-- Connection listings page through a user's connections by creation time
+CREATE INDEX IF NOT EXISTS connections_id_created_at_idx ON connections (id, created_at, connection_id);
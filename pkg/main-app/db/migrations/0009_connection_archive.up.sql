@@ -0,0 +1,3 @@
+-- Deleted connections are kept for audit. Their agent record is gone and
+-- listings hide them unless asked for.
+ALTER TABLE connections ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
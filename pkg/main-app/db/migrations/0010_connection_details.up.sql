@@ -0,0 +1,4 @@
+-- A name and free-form metadata users give their connections. The metadata
+-- and alias are mirrored to the agent's connection metadata.
+ALTER TABLE connections ADD COLUMN IF NOT EXISTS alias TEXT NOT NULL DEFAULT '';
+ALTER TABLE connections ADD COLUMN IF NOT EXISTS metadata JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,3 @@
+ALTER TABLE connections DROP COLUMN IF EXISTS stale;
+ALTER TABLE connections DROP COLUMN IF EXISTS last_seen_at;
+ALTER TABLE connections DROP COLUMN IF EXISTS last_ping_at;
@@ -0,0 +1,6 @@
+-- Trust pings sent over a connection and the last time the other agent was
+-- heard from. The liveness sweep flags active connections not heard from in
+-- a while as stale.
+ALTER TABLE connections ADD COLUMN IF NOT EXISTS last_ping_at TIMESTAMPTZ;
+ALTER TABLE connections ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ;
+ALTER TABLE connections ADD COLUMN IF NOT EXISTS stale BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,17 @@
+-- Basic messages exchanged over a connection. direction is sent or
+-- received; message_id is the agent's ID of received messages, which makes
+-- repeated webhook deliveries harmless.
+
+CREATE TABLE IF NOT EXISTS basic_messages (
+    id BIGSERIAL NOT NULL,
+    connection_id VARCHAR NOT NULL,
+    direction TEXT NOT NULL,
+    content TEXT NOT NULL,
+    message_id TEXT NOT NULL DEFAULT '',
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (id),
+    FOREIGN KEY (connection_id) REFERENCES connections(connection_id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS basic_messages_connection_id_idx ON basic_messages (connection_id, id);
+CREATE UNIQUE INDEX IF NOT EXISTS basic_messages_message_id_idx ON basic_messages (connection_id, message_id) WHERE message_id <> '';
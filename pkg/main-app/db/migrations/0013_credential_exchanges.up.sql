@@ -0,0 +1,25 @@
+-- Credential exchanges as seen by one side. Issuers record the credentials
+-- they send, holders the offers their agent receives. user_id owns the
+-- connection on that side and counterparty_email is the other party's
+-- address. attributes is the JSON of the offered values, AES-GCM sealed
+-- when attributes_encrypted is set.
+
+CREATE TABLE IF NOT EXISTS credential_exchanges (
+    cred_ex_id VARCHAR NOT NULL,
+    role TEXT NOT NULL,
+    user_id BIGINT NOT NULL,
+    connection_id VARCHAR NOT NULL DEFAULT '',
+    counterparty_email TEXT NOT NULL DEFAULT '',
+    thread_id TEXT NOT NULL DEFAULT '',
+    schema_id TEXT NOT NULL DEFAULT '',
+    cred_def_id TEXT NOT NULL DEFAULT '',
+    attributes BYTEA,
+    attributes_encrypted BOOLEAN NOT NULL DEFAULT false,
+    state TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (cred_ex_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS credential_exchanges_user_id_idx ON credential_exchanges (user_id, role, created_at);
+CREATE INDEX IF NOT EXISTS credential_exchanges_connection_id_idx ON credential_exchanges (connection_id);
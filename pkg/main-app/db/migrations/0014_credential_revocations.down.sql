@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS credential_revocations;
+DROP INDEX IF EXISTS credential_exchanges_revocation_idx;
+ALTER TABLE credential_exchanges DROP COLUMN IF EXISTS cred_rev_id;
+ALTER TABLE credential_exchanges DROP COLUMN IF EXISTS rev_reg_id;
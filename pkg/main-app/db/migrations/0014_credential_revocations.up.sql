@@ -0,0 +1,25 @@
+-- Revocations issued through DigiAuth, kept as an audit of who revoked
+-- which credential, when and why. published_at stays NULL until the
+-- revocation is published to the ledger.
+
+ALTER TABLE credential_exchanges ADD COLUMN IF NOT EXISTS rev_reg_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE credential_exchanges ADD COLUMN IF NOT EXISTS cred_rev_id TEXT NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS credential_exchanges_revocation_idx ON credential_exchanges (rev_reg_id, cred_rev_id);
+
+CREATE TABLE IF NOT EXISTS credential_revocations (
+    id BIGSERIAL NOT NULL,
+    cred_ex_id VARCHAR NOT NULL,
+    rev_reg_id TEXT NOT NULL DEFAULT '',
+    cred_rev_id TEXT NOT NULL DEFAULT '',
+    connection_id VARCHAR NOT NULL DEFAULT '',
+    revoked_by BIGINT NOT NULL,
+    revoked_by_email TEXT NOT NULL,
+    reason TEXT NOT NULL DEFAULT '',
+    notified BOOLEAN NOT NULL DEFAULT false,
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    published_at TIMESTAMPTZ,
+    PRIMARY KEY (id),
+    UNIQUE (cred_ex_id),
+    FOREIGN KEY (revoked_by) REFERENCES users(id)
+);
+CREATE INDEX IF NOT EXISTS credential_revocations_revoked_by_idx ON credential_revocations (revoked_by, revoked_at);
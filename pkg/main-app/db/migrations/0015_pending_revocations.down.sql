@@ -0,0 +1 @@
+DROP INDEX IF EXISTS credential_revocations_pending_idx;
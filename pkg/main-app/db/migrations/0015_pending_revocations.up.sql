@@ -0,0 +1,3 @@
+-- Revocations not yet published to the ledger, picked up by the batch
+-- publisher
+CREATE INDEX IF NOT EXISTS credential_revocations_pending_idx ON credential_revocations (id) WHERE published_at IS NULL;
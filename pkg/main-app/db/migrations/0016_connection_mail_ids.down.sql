@@ -0,0 +1,2 @@
+ALTER TABLE connections ALTER COLUMN their_mail_id DROP NOT NULL;
+ALTER TABLE connections ALTER COLUMN my_mail_id DROP NOT NULL;
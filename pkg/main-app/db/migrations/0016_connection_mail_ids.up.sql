@@ -0,0 +1,7 @@
+-- Every connection is made between two known email addresses. The columns
+-- were nullable since the first schema although nothing writes NULL, which
+-- kept the generated models from matching the schema.
+UPDATE connections SET my_mail_id = '' WHERE my_mail_id IS NULL;
+UPDATE connections SET their_mail_id = '' WHERE their_mail_id IS NULL;
+ALTER TABLE connections ALTER COLUMN my_mail_id SET NOT NULL;
+ALTER TABLE connections ALTER COLUMN their_mail_id SET NOT NULL;
@@ -0,0 +1,20 @@
+-- Each connection lives in one role's agent. Recording which lets a server
+-- refuse connections of the other agents. Rows the backfill cannot place are
+-- claimed by the first agent that reports them.
+ALTER TABLE connections ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT '';
+
+UPDATE connections c
+SET role = e.role
+FROM (
+    SELECT DISTINCT ON (connection_id) connection_id, role
+    FROM connection_events
+    ORDER BY connection_id, created_at, id
+) e
+WHERE c.connection_id = e.connection_id AND c.role = '';
+
+UPDATE connections c
+SET role = i.role
+FROM invitations i
+WHERE c.connection_id = i.connection_id AND c.role = '';
+
+ALTER TABLE connections ALTER COLUMN role DROP DEFAULT;
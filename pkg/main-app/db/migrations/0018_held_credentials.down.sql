@@ -0,0 +1 @@
+ALTER TABLE credential_exchanges DROP COLUMN IF EXISTS cred_id;
@@ -0,0 +1,4 @@
+-- The wallet id of the credential a holder stored from an exchange. The
+-- holder agent's wallet is shared, this is what ties a credential in it to
+-- the user it was issued to.
+ALTER TABLE credential_exchanges ADD COLUMN IF NOT EXISTS cred_id TEXT NOT NULL DEFAULT '';
@@ -1,11 +1,21 @@
--- name: GetConnectionsByUserID :many
-SELECT * 
+-- name: GetConnectionByID :one
+SELECT *
 FROM connections
-WHERE id = $1;
+WHERE connection_id = $1;
+
+-- name: GetAuthorizedConnection :one
+-- The connection if it belongs to the user on this role's agent and has not
+-- been deleted
+SELECT *
+FROM connections
+WHERE connection_id = sqlc.arg(connection_id)
+  AND id = sqlc.arg(user_id)
+  AND role = sqlc.arg(role)
+  AND deleted_at IS NULL;
 
 -- name: CreateConnection :exec
-INSERT INTO connections (connection_id, id, my_mail_id, their_mail_id)
-VALUES ($1, $2, $3, $4);
+INSERT INTO connections (connection_id, id, my_mail_id, their_mail_id, role)
+VALUES ($1, $2, $3, $4, $5);
 
 -- name: CreateSchema :exec
 INSERT INTO schemas (schema_id,credential_definition_id,schema_name,attributes)
@@ -23,4 +33,308 @@ FROM schemas WHERE schema_id=$1;
 SELECT *
 FROM connections
 WHERE my_mail_id = $1
-  AND their_mail_id = $2;
\ No newline at end of file
+  AND their_mail_id = $2
+  AND deleted_at IS NULL
+ORDER BY created_at DESC;
+
+-- name: CreateUser :one
+INSERT INTO users (email, name, password_hash)
+VALUES ($1, $2, $3)
+RETURNING *;
+
+-- name: GetUserByID :one
+SELECT *
+FROM users WHERE id = $1;
+
+-- name: GetUserByEmail :one
+SELECT *
+FROM users WHERE email = lower(sqlc.arg(email));
+
+-- name: CreateConnectionEvent :exec
+INSERT INTO connection_events (role, connection_id, state, rfc23_state, their_label, their_did)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: CreateCredentialExchangeEvent :exec
+INSERT INTO credential_exchange_events (role, cred_ex_id, connection_id, thread_id, state)
+VALUES ($1, $2, $3, $4, $5);
+
+-- name: CreatePresentationExchangeEvent :exec
+INSERT INTO presentation_exchange_events (role, pres_ex_id, connection_id, thread_id, state, verified)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: GetLatestPresentationExchangeEvent :one
+SELECT *
+FROM presentation_exchange_events
+WHERE role = $1 AND pres_ex_id = $2
+ORDER BY created_at DESC, id DESC
+LIMIT 1;
+
+-- name: GetLatestConnectionPresentationEvent :one
+SELECT *
+FROM presentation_exchange_events
+WHERE role = $1 AND connection_id = $2
+ORDER BY created_at DESC, id DESC
+LIMIT 1;
+
+-- name: CreateRevocationRegistryEvent :exec
+INSERT INTO revocation_registry_events (role, rev_reg_id, cred_def_id, state)
+VALUES ($1, $2, $3, $4);
+
+-- name: CreateInvitation :exec
+INSERT INTO invitations (connection_id, role, invitation, invitation_id, user_id, recipient_email, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetInvitation :one
+SELECT *
+FROM invitations WHERE connection_id = $1;
+
+-- name: GetInvitationByMessageID :one
+SELECT *
+FROM invitations WHERE invitation_id = $1
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: ListInvitations :many
+SELECT *
+FROM invitations
+WHERE user_id = $1 AND role = $2
+ORDER BY created_at DESC;
+
+-- name: RevokeInvitation :execrows
+UPDATE invitations
+SET status = 'revoked', revoked_at = now()
+WHERE connection_id = $1 AND user_id = $2 AND status = 'pending';
+
+-- name: AcceptInvitation :execrows
+UPDATE invitations
+SET status = 'accepted', accepted_at = now()
+WHERE connection_id = $1 AND status = 'pending' AND expires_at > now();
+
+-- name: ReleaseInvitation :exec
+-- Undoes AcceptInvitation when the invitation could not be received after all
+UPDATE invitations
+SET status = 'pending', accepted_at = NULL
+WHERE connection_id = $1 AND status = 'accepted';
+
+-- name: CreateConnectionIfMissing :exec
+INSERT INTO connections (connection_id, id, my_mail_id, their_mail_id, role)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (connection_id) DO NOTHING;
+
+-- name: CreatePublicInvitation :one
+INSERT INTO public_invitations (id, user_id, role, label, connection_id, invitation, invitation_msg_id, invitation_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING *;
+
+-- name: GetPublicInvitation :one
+SELECT *
+FROM public_invitations WHERE id = $1;
+
+-- name: GetPublicInvitationByMessageID :one
+SELECT *
+FROM public_invitations WHERE invitation_msg_id = $1
+LIMIT 1;
+
+-- name: ListPublicInvitations :many
+SELECT *
+FROM public_invitations
+WHERE user_id = $1 AND role = $2
+ORDER BY created_at DESC;
+
+-- name: RevokePublicInvitation :execrows
+UPDATE public_invitations
+SET revoked_at = now()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: FindPublicInvitation :one
+SELECT *
+FROM public_invitations
+WHERE role = sqlc.arg(role)
+  AND revoked_at IS NULL
+  AND ((invitation_msg_id <> '' AND invitation_msg_id = sqlc.arg(invitation_msg_id))
+    OR (invitation_key <> '' AND invitation_key = sqlc.arg(invitation_key)))
+LIMIT 1;
+
+-- name: ListConnections :many
+-- Keyset pagination: the cursor is the (created_at, connection_id) of the
+-- last row of the previous page, in the direction asked for.
+SELECT *
+FROM connections
+WHERE id = sqlc.arg(user_id)
+  AND role = sqlc.arg(role)
+  AND (sqlc.arg(include_archived)::bool OR deleted_at IS NULL)
+  AND (sqlc.arg(state)::text = '' OR state = sqlc.arg(state))
+  AND (sqlc.arg(search)::text = ''
+    OR their_mail_id ILIKE '%' || sqlc.arg(search) || '%'
+    OR their_label ILIKE '%' || sqlc.arg(search) || '%'
+    OR alias ILIKE '%' || sqlc.arg(search) || '%')
+  AND (sqlc.narg(cursor_created_at)::timestamptz IS NULL
+    OR (sqlc.arg(ascending)::bool AND (created_at, connection_id) > (sqlc.narg(cursor_created_at), sqlc.arg(cursor_connection_id)::text))
+    OR (NOT sqlc.arg(ascending)::bool AND (created_at, connection_id) < (sqlc.narg(cursor_created_at), sqlc.arg(cursor_connection_id)::text)))
+ORDER BY
+  CASE WHEN sqlc.arg(ascending)::bool THEN created_at END ASC,
+  CASE WHEN sqlc.arg(ascending)::bool THEN connection_id END ASC,
+  CASE WHEN NOT sqlc.arg(ascending)::bool THEN created_at END DESC,
+  CASE WHEN NOT sqlc.arg(ascending)::bool THEN connection_id END DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: UpdateConnectionState :execrows
+UPDATE connections
+SET state = sqlc.arg(state),
+    their_label = COALESCE(NULLIF(sqlc.arg(their_label)::text, ''), their_label),
+    their_did = COALESCE(NULLIF(sqlc.arg(their_did)::text, ''), their_did),
+    role = COALESCE(NULLIF(role, ''), sqlc.arg(role)),
+    updated_at = now()
+WHERE connection_id = sqlc.arg(connection_id)
+  AND (state <> sqlc.arg(state)
+    OR (sqlc.arg(their_label) <> '' AND their_label <> sqlc.arg(their_label))
+    OR (sqlc.arg(their_did) <> '' AND their_did <> sqlc.arg(their_did))
+    OR role = '');
+
+-- name: ArchiveConnection :execrows
+UPDATE connections
+SET deleted_at = now(), updated_at = now()
+WHERE connection_id = $1 AND id = $2 AND role = $3 AND deleted_at IS NULL;
+
+-- name: UpdateConnectionDetails :one
+UPDATE connections
+SET alias = $1, metadata = $2, updated_at = now()
+WHERE connection_id = $3 AND id = $4 AND deleted_at IS NULL
+RETURNING *;
+
+-- name: RecordConnectionPing :exec
+UPDATE connections
+SET last_ping_at = now()
+WHERE connection_id = $1;
+
+-- name: MarkConnectionSeen :exec
+UPDATE connections
+SET last_seen_at = now(), stale = false
+WHERE connection_id = $1;
+
+-- name: ListLiveConnectionIDs :many
+SELECT connection_id
+FROM connections
+WHERE role = $1
+  AND state IN ('active', 'completed')
+  AND deleted_at IS NULL
+ORDER BY connection_id;
+
+-- name: FlagStaleConnections :execrows
+-- Connections never heard from count from their creation
+UPDATE connections
+SET stale = COALESCE(last_seen_at, created_at) < sqlc.arg(cutoff)
+WHERE connection_id = ANY(sqlc.arg(connection_ids)::text[])
+  AND deleted_at IS NULL
+  AND stale <> (COALESCE(last_seen_at, created_at) < sqlc.arg(cutoff));
+
+-- name: CreateBasicMessage :one
+INSERT INTO basic_messages (connection_id, direction, content, message_id, sent_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (connection_id, message_id) WHERE message_id <> '' DO NOTHING
+RETURNING *;
+
+-- name: ListBasicMessages :many
+-- Newest first, before_id pages back through older messages
+SELECT *
+FROM basic_messages
+WHERE connection_id = sqlc.arg(connection_id)
+  AND (sqlc.arg(before_id)::bigint = 0 OR id < sqlc.arg(before_id))
+ORDER BY id DESC
+LIMIT sqlc.arg(row_limit);
+
+-- name: CreateCredentialExchange :exec
+INSERT INTO credential_exchanges (cred_ex_id, role, user_id, connection_id, counterparty_email, thread_id, schema_id, cred_def_id, attributes, attributes_encrypted, state)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (cred_ex_id) DO NOTHING;
+
+-- name: UpsertCredentialExchange :exec
+-- Webhooks can arrive before the issuer has recorded the exchange it started,
+-- the state they carry is the newest one
+INSERT INTO credential_exchanges (cred_ex_id, role, user_id, connection_id, counterparty_email, thread_id, schema_id, cred_def_id, attributes, attributes_encrypted, state)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (cred_ex_id) DO UPDATE
+SET state = EXCLUDED.state,
+    thread_id = COALESCE(NULLIF(EXCLUDED.thread_id, ''), credential_exchanges.thread_id),
+    updated_at = now();
+
+-- name: UpdateCredentialExchangeState :execrows
+UPDATE credential_exchanges
+SET state = sqlc.arg(state),
+    thread_id = COALESCE(NULLIF(sqlc.arg(thread_id)::text, ''), thread_id),
+    updated_at = now()
+WHERE cred_ex_id = sqlc.arg(cred_ex_id);
+
+-- name: GetCredentialExchange :one
+SELECT *
+FROM credential_exchanges WHERE cred_ex_id = $1;
+
+-- name: ListCredentialExchanges :many
+SELECT *
+FROM credential_exchanges
+WHERE user_id = sqlc.arg(user_id)
+  AND role = sqlc.arg(role)
+  AND (sqlc.arg(connection_id)::text = '' OR connection_id = sqlc.arg(connection_id))
+  AND (sqlc.arg(counterparty_email)::text = '' OR lower(counterparty_email) = lower(sqlc.arg(counterparty_email)))
+  AND (sqlc.arg(state)::text = '' OR state = sqlc.arg(state))
+ORDER BY created_at DESC, cred_ex_id DESC
+LIMIT sqlc.arg(row_limit) OFFSET sqlc.arg(row_offset);
+
+-- name: SetCredentialRevocationEntry :exec
+UPDATE credential_exchanges
+SET rev_reg_id = $1, cred_rev_id = $2, updated_at = now()
+WHERE cred_ex_id = $3;
+
+-- name: SetStoredCredentialID :exec
+UPDATE credential_exchanges
+SET cred_id = $1, updated_at = now()
+WHERE cred_ex_id = $2;
+
+-- name: ListHeldCredentialIDs :many
+SELECT cred_id
+FROM credential_exchanges
+WHERE user_id = $1 AND role = $2 AND cred_id <> '';
+
+-- name: GetCredentialExchangeByRevocationEntry :one
+SELECT *
+FROM credential_exchanges
+WHERE rev_reg_id = $1 AND cred_rev_id = $2
+LIMIT 1;
+
+-- name: CreateCredentialRevocation :one
+INSERT INTO credential_revocations (cred_ex_id, rev_reg_id, cred_rev_id, connection_id, revoked_by, revoked_by_email, reason, notified, published_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetCredentialRevocationByCredExID :one
+SELECT *
+FROM credential_revocations WHERE cred_ex_id = $1;
+
+-- name: ListCredentialRevocations :many
+-- Status is "pending", "published" or empty for both
+SELECT *
+FROM credential_revocations
+WHERE revoked_by = sqlc.arg(revoked_by)
+  AND (sqlc.arg(status)::text = ''
+    OR (sqlc.arg(status) = 'pending' AND published_at IS NULL)
+    OR (sqlc.arg(status) = 'published' AND published_at IS NOT NULL))
+ORDER BY revoked_at DESC, id DESC;
+
+-- name: ListPendingRevocations :many
+-- A zero revoked_by lists the pending revocations of every issuer
+SELECT *
+FROM credential_revocations
+WHERE published_at IS NULL
+  AND (sqlc.arg(revoked_by)::bigint = 0 OR revoked_by = sqlc.arg(revoked_by))
+ORDER BY id;
+
+-- name: SetRevocationEntry :exec
+UPDATE credential_revocations
+SET rev_reg_id = $1, cred_rev_id = $2
+WHERE id = $3;
+
+-- name: MarkRevocationsPublished :execrows
+UPDATE credential_revocations
+SET published_at = now()
+WHERE id = ANY(sqlc.arg(ids)::bigint[])
+  AND published_at IS NULL;
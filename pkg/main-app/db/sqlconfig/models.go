@@ -5,58 +5,139 @@
 package db
 
 import (
-	"database/sql/driver"
-	"fmt"
+	"encoding/json"
 
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-type RoleEnum string
+type BasicMessage struct {
+	ID           int64
+	ConnectionID string
+	Direction    string
+	Content      string
+	MessageID    string
+	SentAt       pgtype.Timestamptz
+	CreatedAt    pgtype.Timestamptz
+}
 
-const (
-	RoleEnumInviter RoleEnum = "inviter"
-	RoleEnumInvitee RoleEnum = "invitee"
-)
+type Connection struct {
+	ConnectionID string
+	ID           int64
+	MyMailID     string
+	TheirMailID  string
+	State        string
+	TheirLabel   string
+	TheirDid     string
+	CreatedAt    pgtype.Timestamptz
+	UpdatedAt    pgtype.Timestamptz
+	DeletedAt    pgtype.Timestamptz
+	Alias        string
+	Metadata     json.RawMessage
+	LastPingAt   pgtype.Timestamptz
+	LastSeenAt   pgtype.Timestamptz
+	Stale        bool
+	Role         string
+}
+
+type ConnectionEvent struct {
+	ID           int64
+	Role         string
+	ConnectionID string
+	State        string
+	Rfc23State   string
+	TheirLabel   string
+	TheirDid     string
+	CreatedAt    pgtype.Timestamptz
+}
 
-func (e *RoleEnum) Scan(src interface{}) error {
-	switch s := src.(type) {
-	case []byte:
-		*e = RoleEnum(s)
-	case string:
-		*e = RoleEnum(s)
-	default:
-		return fmt.Errorf("unsupported scan type for RoleEnum: %T", src)
-	}
-	return nil
+type CredentialExchange struct {
+	CredExID            string
+	Role                string
+	UserID              int64
+	ConnectionID        string
+	CounterpartyEmail   string
+	ThreadID            string
+	SchemaID            string
+	CredDefID           string
+	Attributes          []byte
+	AttributesEncrypted bool
+	State               string
+	CreatedAt           pgtype.Timestamptz
+	UpdatedAt           pgtype.Timestamptz
+	RevRegID            string
+	CredRevID           string
+	CredID              string
 }
 
-type NullRoleEnum struct {
-	RoleEnum RoleEnum
-	Valid    bool // Valid is true if RoleEnum is not NULL
+type CredentialExchangeEvent struct {
+	ID           int64
+	Role         string
+	CredExID     string
+	ConnectionID string
+	ThreadID     string
+	State        string
+	CreatedAt    pgtype.Timestamptz
 }
 
-// Scan implements the Scanner interface.
-func (ns *NullRoleEnum) Scan(value interface{}) error {
-	if value == nil {
-		ns.RoleEnum, ns.Valid = "", false
-		return nil
-	}
-	ns.Valid = true
-	return ns.RoleEnum.Scan(value)
+type CredentialRevocation struct {
+	ID             int64
+	CredExID       string
+	RevRegID       string
+	CredRevID      string
+	ConnectionID   string
+	RevokedBy      int64
+	RevokedByEmail string
+	Reason         string
+	Notified       bool
+	RevokedAt      pgtype.Timestamptz
+	PublishedAt    pgtype.Timestamptz
 }
 
-// Value implements the driver Valuer interface.
-func (ns NullRoleEnum) Value() (driver.Value, error) {
-	if !ns.Valid {
-		return nil, nil
-	}
-	return string(ns.RoleEnum), nil
+type Invitation struct {
+	ConnectionID   string
+	Role           string
+	Invitation     []byte
+	CreatedAt      pgtype.Timestamptz
+	InvitationID   string
+	UserID         int64
+	RecipientEmail string
+	Status         string
+	ExpiresAt      pgtype.Timestamptz
+	AcceptedAt     pgtype.Timestamptz
+	RevokedAt      pgtype.Timestamptz
 }
 
-type Connection struct {
-	ConnectionID string
+type PresentationExchangeEvent struct {
 	ID           int64
-	MyMailID     string
-	TheirMailID  string
+	Role         string
+	PresExID     string
+	ConnectionID string
+	ThreadID     string
+	State        string
+	Verified     string
+	CreatedAt    pgtype.Timestamptz
+}
+
+type PublicInvitation struct {
+	ID              string
+	UserID          int64
+	Role            string
+	Label           string
+	ConnectionID    string
+	Invitation      []byte
+	InvitationMsgID string
+	InvitationKey   string
+	CreatedAt       pgtype.Timestamptz
+	RevokedAt       pgtype.Timestamptz
+}
+
+type RevocationRegistryEvent struct {
+	ID        int64
+	Role      string
+	RevRegID  string
+	CredDefID string
+	State     string
+	CreatedAt pgtype.Timestamptz
 }
 
 type Schema struct {
@@ -65,3 +146,12 @@ type Schema struct {
 	SchemaName             string
 	Attributes             []string
 }
+
+type User struct {
+	ID           int64
+	Email        string
+	Name         string
+	CreatedAt    pgtype.Timestamptz
+	PasswordHash string
+	UpdatedAt    pgtype.Timestamptz
+}
@@ -7,12 +7,84 @@ package db
 
 import (
 	"context"
+	"encoding/json"
 
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const acceptInvitation = `-- name: AcceptInvitation :execrows
+UPDATE invitations
+SET status = 'accepted', accepted_at = now()
+WHERE connection_id = $1 AND status = 'pending' AND expires_at > now()
+`
+
+func (q *Queries) AcceptInvitation(ctx context.Context, connectionID string) (int64, error) {
+	result, err := q.db.Exec(ctx, acceptInvitation, connectionID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const archiveConnection = `-- name: ArchiveConnection :execrows
+UPDATE connections
+SET deleted_at = now(), updated_at = now()
+WHERE connection_id = $1 AND id = $2 AND role = $3 AND deleted_at IS NULL
+`
+
+type ArchiveConnectionParams struct {
+	ConnectionID string
+	ID           int64
+	Role         string
+}
+
+func (q *Queries) ArchiveConnection(ctx context.Context, arg ArchiveConnectionParams) (int64, error) {
+	result, err := q.db.Exec(ctx, archiveConnection, arg.ConnectionID, arg.ID, arg.Role)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const createBasicMessage = `-- name: CreateBasicMessage :one
+INSERT INTO basic_messages (connection_id, direction, content, message_id, sent_at)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (connection_id, message_id) WHERE message_id <> '' DO NOTHING
+RETURNING id, connection_id, direction, content, message_id, sent_at, created_at
+`
+
+type CreateBasicMessageParams struct {
+	ConnectionID string
+	Direction    string
+	Content      string
+	MessageID    string
+	SentAt       pgtype.Timestamptz
+}
+
+func (q *Queries) CreateBasicMessage(ctx context.Context, arg CreateBasicMessageParams) (BasicMessage, error) {
+	row := q.db.QueryRow(ctx, createBasicMessage,
+		arg.ConnectionID,
+		arg.Direction,
+		arg.Content,
+		arg.MessageID,
+		arg.SentAt,
+	)
+	var i BasicMessage
+	err := row.Scan(
+		&i.ID,
+		&i.ConnectionID,
+		&i.Direction,
+		&i.Content,
+		&i.MessageID,
+		&i.SentAt,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
 const createConnection = `-- name: CreateConnection :exec
-INSERT INTO connections (connection_id, id, my_mail_id, their_mail_id)
-VALUES ($1, $2, $3, $4)
+INSERT INTO connections (connection_id, id, my_mail_id, their_mail_id, role)
+VALUES ($1, $2, $3, $4, $5)
 `
 
 type CreateConnectionParams struct {
@@ -20,6 +92,7 @@ type CreateConnectionParams struct {
 	ID           int64
 	MyMailID     string
 	TheirMailID  string
+	Role         string
 }
 
 func (q *Queries) CreateConnection(ctx context.Context, arg CreateConnectionParams) error {
@@ -28,6 +101,286 @@ func (q *Queries) CreateConnection(ctx context.Context, arg CreateConnectionPara
 		arg.ID,
 		arg.MyMailID,
 		arg.TheirMailID,
+		arg.Role,
+	)
+	return err
+}
+
+const createConnectionEvent = `-- name: CreateConnectionEvent :exec
+INSERT INTO connection_events (role, connection_id, state, rfc23_state, their_label, their_did)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type CreateConnectionEventParams struct {
+	Role         string
+	ConnectionID string
+	State        string
+	Rfc23State   string
+	TheirLabel   string
+	TheirDid     string
+}
+
+func (q *Queries) CreateConnectionEvent(ctx context.Context, arg CreateConnectionEventParams) error {
+	_, err := q.db.Exec(ctx, createConnectionEvent,
+		arg.Role,
+		arg.ConnectionID,
+		arg.State,
+		arg.Rfc23State,
+		arg.TheirLabel,
+		arg.TheirDid,
+	)
+	return err
+}
+
+const createConnectionIfMissing = `-- name: CreateConnectionIfMissing :exec
+INSERT INTO connections (connection_id, id, my_mail_id, their_mail_id, role)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (connection_id) DO NOTHING
+`
+
+type CreateConnectionIfMissingParams struct {
+	ConnectionID string
+	ID           int64
+	MyMailID     string
+	TheirMailID  string
+	Role         string
+}
+
+func (q *Queries) CreateConnectionIfMissing(ctx context.Context, arg CreateConnectionIfMissingParams) error {
+	_, err := q.db.Exec(ctx, createConnectionIfMissing,
+		arg.ConnectionID,
+		arg.ID,
+		arg.MyMailID,
+		arg.TheirMailID,
+		arg.Role,
+	)
+	return err
+}
+
+const createCredentialExchange = `-- name: CreateCredentialExchange :exec
+INSERT INTO credential_exchanges (cred_ex_id, role, user_id, connection_id, counterparty_email, thread_id, schema_id, cred_def_id, attributes, attributes_encrypted, state)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (cred_ex_id) DO NOTHING
+`
+
+type CreateCredentialExchangeParams struct {
+	CredExID            string
+	Role                string
+	UserID              int64
+	ConnectionID        string
+	CounterpartyEmail   string
+	ThreadID            string
+	SchemaID            string
+	CredDefID           string
+	Attributes          []byte
+	AttributesEncrypted bool
+	State               string
+}
+
+func (q *Queries) CreateCredentialExchange(ctx context.Context, arg CreateCredentialExchangeParams) error {
+	_, err := q.db.Exec(ctx, createCredentialExchange,
+		arg.CredExID,
+		arg.Role,
+		arg.UserID,
+		arg.ConnectionID,
+		arg.CounterpartyEmail,
+		arg.ThreadID,
+		arg.SchemaID,
+		arg.CredDefID,
+		arg.Attributes,
+		arg.AttributesEncrypted,
+		arg.State,
+	)
+	return err
+}
+
+const createCredentialExchangeEvent = `-- name: CreateCredentialExchangeEvent :exec
+INSERT INTO credential_exchange_events (role, cred_ex_id, connection_id, thread_id, state)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+type CreateCredentialExchangeEventParams struct {
+	Role         string
+	CredExID     string
+	ConnectionID string
+	ThreadID     string
+	State        string
+}
+
+func (q *Queries) CreateCredentialExchangeEvent(ctx context.Context, arg CreateCredentialExchangeEventParams) error {
+	_, err := q.db.Exec(ctx, createCredentialExchangeEvent,
+		arg.Role,
+		arg.CredExID,
+		arg.ConnectionID,
+		arg.ThreadID,
+		arg.State,
+	)
+	return err
+}
+
+const createCredentialRevocation = `-- name: CreateCredentialRevocation :one
+INSERT INTO credential_revocations (cred_ex_id, rev_reg_id, cred_rev_id, connection_id, revoked_by, revoked_by_email, reason, notified, published_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, cred_ex_id, rev_reg_id, cred_rev_id, connection_id, revoked_by, revoked_by_email, reason, notified, revoked_at, published_at
+`
+
+type CreateCredentialRevocationParams struct {
+	CredExID       string
+	RevRegID       string
+	CredRevID      string
+	ConnectionID   string
+	RevokedBy      int64
+	RevokedByEmail string
+	Reason         string
+	Notified       bool
+	PublishedAt    pgtype.Timestamptz
+}
+
+func (q *Queries) CreateCredentialRevocation(ctx context.Context, arg CreateCredentialRevocationParams) (CredentialRevocation, error) {
+	row := q.db.QueryRow(ctx, createCredentialRevocation,
+		arg.CredExID,
+		arg.RevRegID,
+		arg.CredRevID,
+		arg.ConnectionID,
+		arg.RevokedBy,
+		arg.RevokedByEmail,
+		arg.Reason,
+		arg.Notified,
+		arg.PublishedAt,
+	)
+	var i CredentialRevocation
+	err := row.Scan(
+		&i.ID,
+		&i.CredExID,
+		&i.RevRegID,
+		&i.CredRevID,
+		&i.ConnectionID,
+		&i.RevokedBy,
+		&i.RevokedByEmail,
+		&i.Reason,
+		&i.Notified,
+		&i.RevokedAt,
+		&i.PublishedAt,
+	)
+	return i, err
+}
+
+const createInvitation = `-- name: CreateInvitation :exec
+INSERT INTO invitations (connection_id, role, invitation, invitation_id, user_id, recipient_email, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+`
+
+type CreateInvitationParams struct {
+	ConnectionID   string
+	Role           string
+	Invitation     []byte
+	InvitationID   string
+	UserID         int64
+	RecipientEmail string
+	ExpiresAt      pgtype.Timestamptz
+}
+
+func (q *Queries) CreateInvitation(ctx context.Context, arg CreateInvitationParams) error {
+	_, err := q.db.Exec(ctx, createInvitation,
+		arg.ConnectionID,
+		arg.Role,
+		arg.Invitation,
+		arg.InvitationID,
+		arg.UserID,
+		arg.RecipientEmail,
+		arg.ExpiresAt,
+	)
+	return err
+}
+
+const createPresentationExchangeEvent = `-- name: CreatePresentationExchangeEvent :exec
+INSERT INTO presentation_exchange_events (role, pres_ex_id, connection_id, thread_id, state, verified)
+VALUES ($1, $2, $3, $4, $5, $6)
+`
+
+type CreatePresentationExchangeEventParams struct {
+	Role         string
+	PresExID     string
+	ConnectionID string
+	ThreadID     string
+	State        string
+	Verified     string
+}
+
+func (q *Queries) CreatePresentationExchangeEvent(ctx context.Context, arg CreatePresentationExchangeEventParams) error {
+	_, err := q.db.Exec(ctx, createPresentationExchangeEvent,
+		arg.Role,
+		arg.PresExID,
+		arg.ConnectionID,
+		arg.ThreadID,
+		arg.State,
+		arg.Verified,
+	)
+	return err
+}
+
+const createPublicInvitation = `-- name: CreatePublicInvitation :one
+INSERT INTO public_invitations (id, user_id, role, label, connection_id, invitation, invitation_msg_id, invitation_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+RETURNING id, user_id, role, label, connection_id, invitation, invitation_msg_id, invitation_key, created_at, revoked_at
+`
+
+type CreatePublicInvitationParams struct {
+	ID              string
+	UserID          int64
+	Role            string
+	Label           string
+	ConnectionID    string
+	Invitation      []byte
+	InvitationMsgID string
+	InvitationKey   string
+}
+
+func (q *Queries) CreatePublicInvitation(ctx context.Context, arg CreatePublicInvitationParams) (PublicInvitation, error) {
+	row := q.db.QueryRow(ctx, createPublicInvitation,
+		arg.ID,
+		arg.UserID,
+		arg.Role,
+		arg.Label,
+		arg.ConnectionID,
+		arg.Invitation,
+		arg.InvitationMsgID,
+		arg.InvitationKey,
+	)
+	var i PublicInvitation
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Role,
+		&i.Label,
+		&i.ConnectionID,
+		&i.Invitation,
+		&i.InvitationMsgID,
+		&i.InvitationKey,
+		&i.CreatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const createRevocationRegistryEvent = `-- name: CreateRevocationRegistryEvent :exec
+INSERT INTO revocation_registry_events (role, rev_reg_id, cred_def_id, state)
+VALUES ($1, $2, $3, $4)
+`
+
+type CreateRevocationRegistryEventParams struct {
+	Role      string
+	RevRegID  string
+	CredDefID string
+	State     string
+}
+
+func (q *Queries) CreateRevocationRegistryEvent(ctx context.Context, arg CreateRevocationRegistryEventParams) error {
+	_, err := q.db.Exec(ctx, createRevocationRegistryEvent,
+		arg.Role,
+		arg.RevRegID,
+		arg.CredDefID,
+		arg.State,
 	)
 	return err
 }
@@ -54,11 +407,39 @@ func (q *Queries) CreateSchema(ctx context.Context, arg CreateSchemaParams) erro
 	return err
 }
 
+const createUser = `-- name: CreateUser :one
+INSERT INTO users (email, name, password_hash)
+VALUES ($1, $2, $3)
+RETURNING id, email, name, created_at, password_hash, updated_at
+`
+
+type CreateUserParams struct {
+	Email        string
+	Name         string
+	PasswordHash string
+}
+
+func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, error) {
+	row := q.db.QueryRow(ctx, createUser, arg.Email, arg.Name, arg.PasswordHash)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.CreatedAt,
+		&i.PasswordHash,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const fetchConnections = `-- name: FetchConnections :many
-SELECT connection_id, id, my_mail_id, their_mail_id
+SELECT connection_id, id, my_mail_id, their_mail_id, state, their_label, their_did, created_at, updated_at, deleted_at, alias, metadata, last_ping_at, last_seen_at, stale, role
 FROM connections
 WHERE my_mail_id = $1
   AND their_mail_id = $2
+  AND deleted_at IS NULL
+ORDER BY created_at DESC
 `
 
 type FetchConnectionsParams struct {
@@ -80,6 +461,18 @@ func (q *Queries) FetchConnections(ctx context.Context, arg FetchConnectionsPara
 			&i.ID,
 			&i.MyMailID,
 			&i.TheirMailID,
+			&i.State,
+			&i.TheirLabel,
+			&i.TheirDid,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Alias,
+			&i.Metadata,
+			&i.LastPingAt,
+			&i.LastSeenAt,
+			&i.Stale,
+			&i.Role,
 		); err != nil {
 			return nil, err
 		}
@@ -91,80 +484,1168 @@ func (q *Queries) FetchConnections(ctx context.Context, arg FetchConnectionsPara
 	return items, nil
 }
 
-const getConnectionsByUserID = `-- name: GetConnectionsByUserID :many
-SELECT connection_id, id, my_mail_id, their_mail_id 
-FROM connections
-WHERE id = $1
+const findPublicInvitation = `-- name: FindPublicInvitation :one
+SELECT id, user_id, role, label, connection_id, invitation, invitation_msg_id, invitation_key, created_at, revoked_at
+FROM public_invitations
+WHERE role = $1
+  AND revoked_at IS NULL
+  AND ((invitation_msg_id <> '' AND invitation_msg_id = $2)
+    OR (invitation_key <> '' AND invitation_key = $3))
+LIMIT 1
 `
 
-func (q *Queries) GetConnectionsByUserID(ctx context.Context, id int64) ([]Connection, error) {
-	rows, err := q.db.Query(ctx, getConnectionsByUserID, id)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Connection
-	for rows.Next() {
-		var i Connection
-		if err := rows.Scan(
-			&i.ConnectionID,
-			&i.ID,
-			&i.MyMailID,
-			&i.TheirMailID,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+type FindPublicInvitationParams struct {
+	Role            string
+	InvitationMsgID string
+	InvitationKey   string
 }
 
-const getSchema = `-- name: GetSchema :many
-SELECT schema_id, credential_definition_id, schema_name, attributes
-FROM schemas
+func (q *Queries) FindPublicInvitation(ctx context.Context, arg FindPublicInvitationParams) (PublicInvitation, error) {
+	row := q.db.QueryRow(ctx, findPublicInvitation, arg.Role, arg.InvitationMsgID, arg.InvitationKey)
+	var i PublicInvitation
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Role,
+		&i.Label,
+		&i.ConnectionID,
+		&i.Invitation,
+		&i.InvitationMsgID,
+		&i.InvitationKey,
+		&i.CreatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const flagStaleConnections = `-- name: FlagStaleConnections :execrows
+UPDATE connections
+SET stale = COALESCE(last_seen_at, created_at) < $1
+WHERE connection_id = ANY($2::text[])
+  AND deleted_at IS NULL
+  AND stale <> (COALESCE(last_seen_at, created_at) < $1)
 `
 
-func (q *Queries) GetSchema(ctx context.Context) ([]Schema, error) {
-	rows, err := q.db.Query(ctx, getSchema)
+type FlagStaleConnectionsParams struct {
+	Cutoff        pgtype.Timestamptz
+	ConnectionIds []string
+}
+
+// Connections never heard from count from their creation
+func (q *Queries) FlagStaleConnections(ctx context.Context, arg FlagStaleConnectionsParams) (int64, error) {
+	result, err := q.db.Exec(ctx, flagStaleConnections, arg.Cutoff, arg.ConnectionIds)
 	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	var items []Schema
-	for rows.Next() {
-		var i Schema
-		if err := rows.Scan(
-			&i.SchemaID,
-			&i.CredentialDefinitionID,
-			&i.SchemaName,
-			&i.Attributes,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
+		return 0, err
 	}
-	return items, nil
+	return result.RowsAffected(), nil
 }
 
-const getSchemaById = `-- name: GetSchemaById :one
-SELECT schema_id, credential_definition_id, schema_name, attributes
-FROM schemas WHERE schema_id=$1
+const getAuthorizedConnection = `-- name: GetAuthorizedConnection :one
+SELECT connection_id, id, my_mail_id, their_mail_id, state, their_label, their_did, created_at, updated_at, deleted_at, alias, metadata, last_ping_at, last_seen_at, stale, role
+FROM connections
+WHERE connection_id = $1
+  AND id = $2
+  AND role = $3
+  AND deleted_at IS NULL
 `
 
-func (q *Queries) GetSchemaById(ctx context.Context, schemaID string) (Schema, error) {
-	row := q.db.QueryRow(ctx, getSchemaById, schemaID)
-	var i Schema
+type GetAuthorizedConnectionParams struct {
+	ConnectionID string
+	UserID       int64
+	Role         string
+}
+
+// The connection if it belongs to the user on this role's agent and has not
+// been deleted
+func (q *Queries) GetAuthorizedConnection(ctx context.Context, arg GetAuthorizedConnectionParams) (Connection, error) {
+	row := q.db.QueryRow(ctx, getAuthorizedConnection, arg.ConnectionID, arg.UserID, arg.Role)
+	var i Connection
+	err := row.Scan(
+		&i.ConnectionID,
+		&i.ID,
+		&i.MyMailID,
+		&i.TheirMailID,
+		&i.State,
+		&i.TheirLabel,
+		&i.TheirDid,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Alias,
+		&i.Metadata,
+		&i.LastPingAt,
+		&i.LastSeenAt,
+		&i.Stale,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getConnectionByID = `-- name: GetConnectionByID :one
+SELECT connection_id, id, my_mail_id, their_mail_id, state, their_label, their_did, created_at, updated_at, deleted_at, alias, metadata, last_ping_at, last_seen_at, stale, role
+FROM connections
+WHERE connection_id = $1
+`
+
+func (q *Queries) GetConnectionByID(ctx context.Context, connectionID string) (Connection, error) {
+	row := q.db.QueryRow(ctx, getConnectionByID, connectionID)
+	var i Connection
 	err := row.Scan(
+		&i.ConnectionID,
+		&i.ID,
+		&i.MyMailID,
+		&i.TheirMailID,
+		&i.State,
+		&i.TheirLabel,
+		&i.TheirDid,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Alias,
+		&i.Metadata,
+		&i.LastPingAt,
+		&i.LastSeenAt,
+		&i.Stale,
+		&i.Role,
+	)
+	return i, err
+}
+
+const getCredentialExchange = `-- name: GetCredentialExchange :one
+SELECT cred_ex_id, role, user_id, connection_id, counterparty_email, thread_id, schema_id, cred_def_id, attributes, attributes_encrypted, state, created_at, updated_at, rev_reg_id, cred_rev_id, cred_id
+FROM credential_exchanges WHERE cred_ex_id = $1
+`
+
+func (q *Queries) GetCredentialExchange(ctx context.Context, credExID string) (CredentialExchange, error) {
+	row := q.db.QueryRow(ctx, getCredentialExchange, credExID)
+	var i CredentialExchange
+	err := row.Scan(
+		&i.CredExID,
+		&i.Role,
+		&i.UserID,
+		&i.ConnectionID,
+		&i.CounterpartyEmail,
+		&i.ThreadID,
 		&i.SchemaID,
-		&i.CredentialDefinitionID,
-		&i.SchemaName,
+		&i.CredDefID,
+		&i.Attributes,
+		&i.AttributesEncrypted,
+		&i.State,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.RevRegID,
+		&i.CredRevID,
+		&i.CredID,
+	)
+	return i, err
+}
+
+const getCredentialExchangeByRevocationEntry = `-- name: GetCredentialExchangeByRevocationEntry :one
+SELECT cred_ex_id, role, user_id, connection_id, counterparty_email, thread_id, schema_id, cred_def_id, attributes, attributes_encrypted, state, created_at, updated_at, rev_reg_id, cred_rev_id, cred_id
+FROM credential_exchanges
+WHERE rev_reg_id = $1 AND cred_rev_id = $2
+LIMIT 1
+`
+
+type GetCredentialExchangeByRevocationEntryParams struct {
+	RevRegID  string
+	CredRevID string
+}
+
+func (q *Queries) GetCredentialExchangeByRevocationEntry(ctx context.Context, arg GetCredentialExchangeByRevocationEntryParams) (CredentialExchange, error) {
+	row := q.db.QueryRow(ctx, getCredentialExchangeByRevocationEntry, arg.RevRegID, arg.CredRevID)
+	var i CredentialExchange
+	err := row.Scan(
+		&i.CredExID,
+		&i.Role,
+		&i.UserID,
+		&i.ConnectionID,
+		&i.CounterpartyEmail,
+		&i.ThreadID,
+		&i.SchemaID,
+		&i.CredDefID,
 		&i.Attributes,
+		&i.AttributesEncrypted,
+		&i.State,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.RevRegID,
+		&i.CredRevID,
+		&i.CredID,
+	)
+	return i, err
+}
+
+const getCredentialRevocationByCredExID = `-- name: GetCredentialRevocationByCredExID :one
+SELECT id, cred_ex_id, rev_reg_id, cred_rev_id, connection_id, revoked_by, revoked_by_email, reason, notified, revoked_at, published_at
+FROM credential_revocations WHERE cred_ex_id = $1
+`
+
+func (q *Queries) GetCredentialRevocationByCredExID(ctx context.Context, credExID string) (CredentialRevocation, error) {
+	row := q.db.QueryRow(ctx, getCredentialRevocationByCredExID, credExID)
+	var i CredentialRevocation
+	err := row.Scan(
+		&i.ID,
+		&i.CredExID,
+		&i.RevRegID,
+		&i.CredRevID,
+		&i.ConnectionID,
+		&i.RevokedBy,
+		&i.RevokedByEmail,
+		&i.Reason,
+		&i.Notified,
+		&i.RevokedAt,
+		&i.PublishedAt,
+	)
+	return i, err
+}
+
+const getInvitation = `-- name: GetInvitation :one
+SELECT connection_id, role, invitation, created_at, invitation_id, user_id, recipient_email, status, expires_at, accepted_at, revoked_at
+FROM invitations WHERE connection_id = $1
+`
+
+func (q *Queries) GetInvitation(ctx context.Context, connectionID string) (Invitation, error) {
+	row := q.db.QueryRow(ctx, getInvitation, connectionID)
+	var i Invitation
+	err := row.Scan(
+		&i.ConnectionID,
+		&i.Role,
+		&i.Invitation,
+		&i.CreatedAt,
+		&i.InvitationID,
+		&i.UserID,
+		&i.RecipientEmail,
+		&i.Status,
+		&i.ExpiresAt,
+		&i.AcceptedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getInvitationByMessageID = `-- name: GetInvitationByMessageID :one
+SELECT connection_id, role, invitation, created_at, invitation_id, user_id, recipient_email, status, expires_at, accepted_at, revoked_at
+FROM invitations WHERE invitation_id = $1
+ORDER BY created_at DESC
+LIMIT 1
+`
+
+func (q *Queries) GetInvitationByMessageID(ctx context.Context, invitationID string) (Invitation, error) {
+	row := q.db.QueryRow(ctx, getInvitationByMessageID, invitationID)
+	var i Invitation
+	err := row.Scan(
+		&i.ConnectionID,
+		&i.Role,
+		&i.Invitation,
+		&i.CreatedAt,
+		&i.InvitationID,
+		&i.UserID,
+		&i.RecipientEmail,
+		&i.Status,
+		&i.ExpiresAt,
+		&i.AcceptedAt,
+		&i.RevokedAt,
 	)
 	return i, err
 }
+
+const getLatestConnectionPresentationEvent = `-- name: GetLatestConnectionPresentationEvent :one
+SELECT id, role, pres_ex_id, connection_id, thread_id, state, verified, created_at
+FROM presentation_exchange_events
+WHERE role = $1 AND connection_id = $2
+ORDER BY created_at DESC, id DESC
+LIMIT 1
+`
+
+type GetLatestConnectionPresentationEventParams struct {
+	Role         string
+	ConnectionID string
+}
+
+func (q *Queries) GetLatestConnectionPresentationEvent(ctx context.Context, arg GetLatestConnectionPresentationEventParams) (PresentationExchangeEvent, error) {
+	row := q.db.QueryRow(ctx, getLatestConnectionPresentationEvent, arg.Role, arg.ConnectionID)
+	var i PresentationExchangeEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Role,
+		&i.PresExID,
+		&i.ConnectionID,
+		&i.ThreadID,
+		&i.State,
+		&i.Verified,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getLatestPresentationExchangeEvent = `-- name: GetLatestPresentationExchangeEvent :one
+SELECT id, role, pres_ex_id, connection_id, thread_id, state, verified, created_at
+FROM presentation_exchange_events
+WHERE role = $1 AND pres_ex_id = $2
+ORDER BY created_at DESC, id DESC
+LIMIT 1
+`
+
+type GetLatestPresentationExchangeEventParams struct {
+	Role     string
+	PresExID string
+}
+
+func (q *Queries) GetLatestPresentationExchangeEvent(ctx context.Context, arg GetLatestPresentationExchangeEventParams) (PresentationExchangeEvent, error) {
+	row := q.db.QueryRow(ctx, getLatestPresentationExchangeEvent, arg.Role, arg.PresExID)
+	var i PresentationExchangeEvent
+	err := row.Scan(
+		&i.ID,
+		&i.Role,
+		&i.PresExID,
+		&i.ConnectionID,
+		&i.ThreadID,
+		&i.State,
+		&i.Verified,
+		&i.CreatedAt,
+	)
+	return i, err
+}
+
+const getPublicInvitation = `-- name: GetPublicInvitation :one
+SELECT id, user_id, role, label, connection_id, invitation, invitation_msg_id, invitation_key, created_at, revoked_at
+FROM public_invitations WHERE id = $1
+`
+
+func (q *Queries) GetPublicInvitation(ctx context.Context, id string) (PublicInvitation, error) {
+	row := q.db.QueryRow(ctx, getPublicInvitation, id)
+	var i PublicInvitation
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Role,
+		&i.Label,
+		&i.ConnectionID,
+		&i.Invitation,
+		&i.InvitationMsgID,
+		&i.InvitationKey,
+		&i.CreatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getPublicInvitationByMessageID = `-- name: GetPublicInvitationByMessageID :one
+SELECT id, user_id, role, label, connection_id, invitation, invitation_msg_id, invitation_key, created_at, revoked_at
+FROM public_invitations WHERE invitation_msg_id = $1
+LIMIT 1
+`
+
+func (q *Queries) GetPublicInvitationByMessageID(ctx context.Context, invitationMsgID string) (PublicInvitation, error) {
+	row := q.db.QueryRow(ctx, getPublicInvitationByMessageID, invitationMsgID)
+	var i PublicInvitation
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.Role,
+		&i.Label,
+		&i.ConnectionID,
+		&i.Invitation,
+		&i.InvitationMsgID,
+		&i.InvitationKey,
+		&i.CreatedAt,
+		&i.RevokedAt,
+	)
+	return i, err
+}
+
+const getSchema = `-- name: GetSchema :many
+SELECT schema_id, credential_definition_id, schema_name, attributes
+FROM schemas
+`
+
+func (q *Queries) GetSchema(ctx context.Context) ([]Schema, error) {
+	rows, err := q.db.Query(ctx, getSchema)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Schema
+	for rows.Next() {
+		var i Schema
+		if err := rows.Scan(
+			&i.SchemaID,
+			&i.CredentialDefinitionID,
+			&i.SchemaName,
+			&i.Attributes,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getSchemaById = `-- name: GetSchemaById :one
+SELECT schema_id, credential_definition_id, schema_name, attributes
+FROM schemas WHERE schema_id=$1
+`
+
+func (q *Queries) GetSchemaById(ctx context.Context, schemaID string) (Schema, error) {
+	row := q.db.QueryRow(ctx, getSchemaById, schemaID)
+	var i Schema
+	err := row.Scan(
+		&i.SchemaID,
+		&i.CredentialDefinitionID,
+		&i.SchemaName,
+		&i.Attributes,
+	)
+	return i, err
+}
+
+const getUserByEmail = `-- name: GetUserByEmail :one
+SELECT id, email, name, created_at, password_hash, updated_at
+FROM users WHERE email = lower($1)
+`
+
+func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByEmail, email)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.CreatedAt,
+		&i.PasswordHash,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getUserByID = `-- name: GetUserByID :one
+SELECT id, email, name, created_at, password_hash, updated_at
+FROM users WHERE id = $1
+`
+
+func (q *Queries) GetUserByID(ctx context.Context, id int64) (User, error) {
+	row := q.db.QueryRow(ctx, getUserByID, id)
+	var i User
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.Name,
+		&i.CreatedAt,
+		&i.PasswordHash,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const listBasicMessages = `-- name: ListBasicMessages :many
+SELECT id, connection_id, direction, content, message_id, sent_at, created_at
+FROM basic_messages
+WHERE connection_id = $1
+  AND ($2::bigint = 0 OR id < $2)
+ORDER BY id DESC
+LIMIT $3
+`
+
+type ListBasicMessagesParams struct {
+	ConnectionID string
+	BeforeID     int64
+	RowLimit     int32
+}
+
+// Newest first, before_id pages back through older messages
+func (q *Queries) ListBasicMessages(ctx context.Context, arg ListBasicMessagesParams) ([]BasicMessage, error) {
+	rows, err := q.db.Query(ctx, listBasicMessages, arg.ConnectionID, arg.BeforeID, arg.RowLimit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []BasicMessage
+	for rows.Next() {
+		var i BasicMessage
+		if err := rows.Scan(
+			&i.ID,
+			&i.ConnectionID,
+			&i.Direction,
+			&i.Content,
+			&i.MessageID,
+			&i.SentAt,
+			&i.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listConnections = `-- name: ListConnections :many
+SELECT connection_id, id, my_mail_id, their_mail_id, state, their_label, their_did, created_at, updated_at, deleted_at, alias, metadata, last_ping_at, last_seen_at, stale, role
+FROM connections
+WHERE id = $1
+  AND role = $2
+  AND ($3::bool OR deleted_at IS NULL)
+  AND ($4::text = '' OR state = $4)
+  AND ($5::text = ''
+    OR their_mail_id ILIKE '%' || $5 || '%'
+    OR their_label ILIKE '%' || $5 || '%'
+    OR alias ILIKE '%' || $5 || '%')
+  AND ($6::timestamptz IS NULL
+    OR ($7::bool AND (created_at, connection_id) > ($6, $8::text))
+    OR (NOT $7::bool AND (created_at, connection_id) < ($6, $8::text)))
+ORDER BY
+  CASE WHEN $7::bool THEN created_at END ASC,
+  CASE WHEN $7::bool THEN connection_id END ASC,
+  CASE WHEN NOT $7::bool THEN created_at END DESC,
+  CASE WHEN NOT $7::bool THEN connection_id END DESC
+LIMIT $9
+`
+
+type ListConnectionsParams struct {
+	UserID             int64
+	Role               string
+	IncludeArchived    bool
+	State              string
+	Search             string
+	CursorCreatedAt    pgtype.Timestamptz
+	Ascending          bool
+	CursorConnectionID string
+	RowLimit           int32
+}
+
+// Keyset pagination: the cursor is the (created_at, connection_id) of the
+// last row of the previous page, in the direction asked for.
+func (q *Queries) ListConnections(ctx context.Context, arg ListConnectionsParams) ([]Connection, error) {
+	rows, err := q.db.Query(ctx, listConnections,
+		arg.UserID,
+		arg.Role,
+		arg.IncludeArchived,
+		arg.State,
+		arg.Search,
+		arg.CursorCreatedAt,
+		arg.Ascending,
+		arg.CursorConnectionID,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Connection
+	for rows.Next() {
+		var i Connection
+		if err := rows.Scan(
+			&i.ConnectionID,
+			&i.ID,
+			&i.MyMailID,
+			&i.TheirMailID,
+			&i.State,
+			&i.TheirLabel,
+			&i.TheirDid,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.DeletedAt,
+			&i.Alias,
+			&i.Metadata,
+			&i.LastPingAt,
+			&i.LastSeenAt,
+			&i.Stale,
+			&i.Role,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCredentialExchanges = `-- name: ListCredentialExchanges :many
+SELECT cred_ex_id, role, user_id, connection_id, counterparty_email, thread_id, schema_id, cred_def_id, attributes, attributes_encrypted, state, created_at, updated_at, rev_reg_id, cred_rev_id, cred_id
+FROM credential_exchanges
+WHERE user_id = $1
+  AND role = $2
+  AND ($3::text = '' OR connection_id = $3)
+  AND ($4::text = '' OR lower(counterparty_email) = lower($4))
+  AND ($5::text = '' OR state = $5)
+ORDER BY created_at DESC, cred_ex_id DESC
+LIMIT $6 OFFSET $7
+`
+
+type ListCredentialExchangesParams struct {
+	UserID            int64
+	Role              string
+	ConnectionID      string
+	CounterpartyEmail string
+	State             string
+	RowLimit          int32
+	RowOffset         int32
+}
+
+func (q *Queries) ListCredentialExchanges(ctx context.Context, arg ListCredentialExchangesParams) ([]CredentialExchange, error) {
+	rows, err := q.db.Query(ctx, listCredentialExchanges,
+		arg.UserID,
+		arg.Role,
+		arg.ConnectionID,
+		arg.CounterpartyEmail,
+		arg.State,
+		arg.RowLimit,
+		arg.RowOffset,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CredentialExchange
+	for rows.Next() {
+		var i CredentialExchange
+		if err := rows.Scan(
+			&i.CredExID,
+			&i.Role,
+			&i.UserID,
+			&i.ConnectionID,
+			&i.CounterpartyEmail,
+			&i.ThreadID,
+			&i.SchemaID,
+			&i.CredDefID,
+			&i.Attributes,
+			&i.AttributesEncrypted,
+			&i.State,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.RevRegID,
+			&i.CredRevID,
+			&i.CredID,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listCredentialRevocations = `-- name: ListCredentialRevocations :many
+SELECT id, cred_ex_id, rev_reg_id, cred_rev_id, connection_id, revoked_by, revoked_by_email, reason, notified, revoked_at, published_at
+FROM credential_revocations
+WHERE revoked_by = $1
+  AND ($2::text = ''
+    OR ($2 = 'pending' AND published_at IS NULL)
+    OR ($2 = 'published' AND published_at IS NOT NULL))
+ORDER BY revoked_at DESC, id DESC
+`
+
+type ListCredentialRevocationsParams struct {
+	RevokedBy int64
+	Status    string
+}
+
+// Status is "pending", "published" or empty for both
+func (q *Queries) ListCredentialRevocations(ctx context.Context, arg ListCredentialRevocationsParams) ([]CredentialRevocation, error) {
+	rows, err := q.db.Query(ctx, listCredentialRevocations, arg.RevokedBy, arg.Status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CredentialRevocation
+	for rows.Next() {
+		var i CredentialRevocation
+		if err := rows.Scan(
+			&i.ID,
+			&i.CredExID,
+			&i.RevRegID,
+			&i.CredRevID,
+			&i.ConnectionID,
+			&i.RevokedBy,
+			&i.RevokedByEmail,
+			&i.Reason,
+			&i.Notified,
+			&i.RevokedAt,
+			&i.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listHeldCredentialIDs = `-- name: ListHeldCredentialIDs :many
+SELECT cred_id
+FROM credential_exchanges
+WHERE user_id = $1 AND role = $2 AND cred_id <> ''
+`
+
+type ListHeldCredentialIDsParams struct {
+	UserID int64
+	Role   string
+}
+
+func (q *Queries) ListHeldCredentialIDs(ctx context.Context, arg ListHeldCredentialIDsParams) ([]string, error) {
+	rows, err := q.db.Query(ctx, listHeldCredentialIDs, arg.UserID, arg.Role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var cred_id string
+		if err := rows.Scan(&cred_id); err != nil {
+			return nil, err
+		}
+		items = append(items, cred_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listInvitations = `-- name: ListInvitations :many
+SELECT connection_id, role, invitation, created_at, invitation_id, user_id, recipient_email, status, expires_at, accepted_at, revoked_at
+FROM invitations
+WHERE user_id = $1 AND role = $2
+ORDER BY created_at DESC
+`
+
+type ListInvitationsParams struct {
+	UserID int64
+	Role   string
+}
+
+func (q *Queries) ListInvitations(ctx context.Context, arg ListInvitationsParams) ([]Invitation, error) {
+	rows, err := q.db.Query(ctx, listInvitations, arg.UserID, arg.Role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Invitation
+	for rows.Next() {
+		var i Invitation
+		if err := rows.Scan(
+			&i.ConnectionID,
+			&i.Role,
+			&i.Invitation,
+			&i.CreatedAt,
+			&i.InvitationID,
+			&i.UserID,
+			&i.RecipientEmail,
+			&i.Status,
+			&i.ExpiresAt,
+			&i.AcceptedAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listLiveConnectionIDs = `-- name: ListLiveConnectionIDs :many
+SELECT connection_id
+FROM connections
+WHERE role = $1
+  AND state IN ('active', 'completed')
+  AND deleted_at IS NULL
+ORDER BY connection_id
+`
+
+func (q *Queries) ListLiveConnectionIDs(ctx context.Context, role string) ([]string, error) {
+	rows, err := q.db.Query(ctx, listLiveConnectionIDs, role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []string
+	for rows.Next() {
+		var connection_id string
+		if err := rows.Scan(&connection_id); err != nil {
+			return nil, err
+		}
+		items = append(items, connection_id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPendingRevocations = `-- name: ListPendingRevocations :many
+SELECT id, cred_ex_id, rev_reg_id, cred_rev_id, connection_id, revoked_by, revoked_by_email, reason, notified, revoked_at, published_at
+FROM credential_revocations
+WHERE published_at IS NULL
+  AND ($1::bigint = 0 OR revoked_by = $1)
+ORDER BY id
+`
+
+// A zero revoked_by lists the pending revocations of every issuer
+func (q *Queries) ListPendingRevocations(ctx context.Context, revokedBy int64) ([]CredentialRevocation, error) {
+	rows, err := q.db.Query(ctx, listPendingRevocations, revokedBy)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []CredentialRevocation
+	for rows.Next() {
+		var i CredentialRevocation
+		if err := rows.Scan(
+			&i.ID,
+			&i.CredExID,
+			&i.RevRegID,
+			&i.CredRevID,
+			&i.ConnectionID,
+			&i.RevokedBy,
+			&i.RevokedByEmail,
+			&i.Reason,
+			&i.Notified,
+			&i.RevokedAt,
+			&i.PublishedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const listPublicInvitations = `-- name: ListPublicInvitations :many
+SELECT id, user_id, role, label, connection_id, invitation, invitation_msg_id, invitation_key, created_at, revoked_at
+FROM public_invitations
+WHERE user_id = $1 AND role = $2
+ORDER BY created_at DESC
+`
+
+type ListPublicInvitationsParams struct {
+	UserID int64
+	Role   string
+}
+
+func (q *Queries) ListPublicInvitations(ctx context.Context, arg ListPublicInvitationsParams) ([]PublicInvitation, error) {
+	rows, err := q.db.Query(ctx, listPublicInvitations, arg.UserID, arg.Role)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PublicInvitation
+	for rows.Next() {
+		var i PublicInvitation
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.Role,
+			&i.Label,
+			&i.ConnectionID,
+			&i.Invitation,
+			&i.InvitationMsgID,
+			&i.InvitationKey,
+			&i.CreatedAt,
+			&i.RevokedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const markConnectionSeen = `-- name: MarkConnectionSeen :exec
+UPDATE connections
+SET last_seen_at = now(), stale = false
+WHERE connection_id = $1
+`
+
+func (q *Queries) MarkConnectionSeen(ctx context.Context, connectionID string) error {
+	_, err := q.db.Exec(ctx, markConnectionSeen, connectionID)
+	return err
+}
+
+const markRevocationsPublished = `-- name: MarkRevocationsPublished :execrows
+UPDATE credential_revocations
+SET published_at = now()
+WHERE id = ANY($1::bigint[])
+  AND published_at IS NULL
+`
+
+func (q *Queries) MarkRevocationsPublished(ctx context.Context, ids []int64) (int64, error) {
+	result, err := q.db.Exec(ctx, markRevocationsPublished, ids)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const recordConnectionPing = `-- name: RecordConnectionPing :exec
+UPDATE connections
+SET last_ping_at = now()
+WHERE connection_id = $1
+`
+
+func (q *Queries) RecordConnectionPing(ctx context.Context, connectionID string) error {
+	_, err := q.db.Exec(ctx, recordConnectionPing, connectionID)
+	return err
+}
+
+const releaseInvitation = `-- name: ReleaseInvitation :exec
+UPDATE invitations
+SET status = 'pending', accepted_at = NULL
+WHERE connection_id = $1 AND status = 'accepted'
+`
+
+// Undoes AcceptInvitation when the invitation could not be received after all
+func (q *Queries) ReleaseInvitation(ctx context.Context, connectionID string) error {
+	_, err := q.db.Exec(ctx, releaseInvitation, connectionID)
+	return err
+}
+
+const revokeInvitation = `-- name: RevokeInvitation :execrows
+UPDATE invitations
+SET status = 'revoked', revoked_at = now()
+WHERE connection_id = $1 AND user_id = $2 AND status = 'pending'
+`
+
+type RevokeInvitationParams struct {
+	ConnectionID string
+	UserID       int64
+}
+
+func (q *Queries) RevokeInvitation(ctx context.Context, arg RevokeInvitationParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokeInvitation, arg.ConnectionID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const revokePublicInvitation = `-- name: RevokePublicInvitation :execrows
+UPDATE public_invitations
+SET revoked_at = now()
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+
+type RevokePublicInvitationParams struct {
+	ID     string
+	UserID int64
+}
+
+func (q *Queries) RevokePublicInvitation(ctx context.Context, arg RevokePublicInvitationParams) (int64, error) {
+	result, err := q.db.Exec(ctx, revokePublicInvitation, arg.ID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const setCredentialRevocationEntry = `-- name: SetCredentialRevocationEntry :exec
+UPDATE credential_exchanges
+SET rev_reg_id = $1, cred_rev_id = $2, updated_at = now()
+WHERE cred_ex_id = $3
+`
+
+type SetCredentialRevocationEntryParams struct {
+	RevRegID  string
+	CredRevID string
+	CredExID  string
+}
+
+func (q *Queries) SetCredentialRevocationEntry(ctx context.Context, arg SetCredentialRevocationEntryParams) error {
+	_, err := q.db.Exec(ctx, setCredentialRevocationEntry, arg.RevRegID, arg.CredRevID, arg.CredExID)
+	return err
+}
+
+const setRevocationEntry = `-- name: SetRevocationEntry :exec
+UPDATE credential_revocations
+SET rev_reg_id = $1, cred_rev_id = $2
+WHERE id = $3
+`
+
+type SetRevocationEntryParams struct {
+	RevRegID  string
+	CredRevID string
+	ID        int64
+}
+
+func (q *Queries) SetRevocationEntry(ctx context.Context, arg SetRevocationEntryParams) error {
+	_, err := q.db.Exec(ctx, setRevocationEntry, arg.RevRegID, arg.CredRevID, arg.ID)
+	return err
+}
+
+const setStoredCredentialID = `-- name: SetStoredCredentialID :exec
+UPDATE credential_exchanges
+SET cred_id = $1, updated_at = now()
+WHERE cred_ex_id = $2
+`
+
+type SetStoredCredentialIDParams struct {
+	CredID   string
+	CredExID string
+}
+
+func (q *Queries) SetStoredCredentialID(ctx context.Context, arg SetStoredCredentialIDParams) error {
+	_, err := q.db.Exec(ctx, setStoredCredentialID, arg.CredID, arg.CredExID)
+	return err
+}
+
+const updateConnectionDetails = `-- name: UpdateConnectionDetails :one
+UPDATE connections
+SET alias = $1, metadata = $2, updated_at = now()
+WHERE connection_id = $3 AND id = $4 AND deleted_at IS NULL
+RETURNING connection_id, id, my_mail_id, their_mail_id, state, their_label, their_did, created_at, updated_at, deleted_at, alias, metadata, last_ping_at, last_seen_at, stale, role
+`
+
+type UpdateConnectionDetailsParams struct {
+	Alias        string
+	Metadata     json.RawMessage
+	ConnectionID string
+	ID           int64
+}
+
+func (q *Queries) UpdateConnectionDetails(ctx context.Context, arg UpdateConnectionDetailsParams) (Connection, error) {
+	row := q.db.QueryRow(ctx, updateConnectionDetails,
+		arg.Alias,
+		arg.Metadata,
+		arg.ConnectionID,
+		arg.ID,
+	)
+	var i Connection
+	err := row.Scan(
+		&i.ConnectionID,
+		&i.ID,
+		&i.MyMailID,
+		&i.TheirMailID,
+		&i.State,
+		&i.TheirLabel,
+		&i.TheirDid,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Alias,
+		&i.Metadata,
+		&i.LastPingAt,
+		&i.LastSeenAt,
+		&i.Stale,
+		&i.Role,
+	)
+	return i, err
+}
+
+const updateConnectionState = `-- name: UpdateConnectionState :execrows
+UPDATE connections
+SET state = $1,
+    their_label = COALESCE(NULLIF($2::text, ''), their_label),
+    their_did = COALESCE(NULLIF($3::text, ''), their_did),
+    role = COALESCE(NULLIF(role, ''), $4),
+    updated_at = now()
+WHERE connection_id = $5
+  AND (state <> $1
+    OR ($2 <> '' AND their_label <> $2)
+    OR ($3 <> '' AND their_did <> $3)
+    OR role = '')
+`
+
+type UpdateConnectionStateParams struct {
+	State        string
+	TheirLabel   string
+	TheirDid     string
+	Role         string
+	ConnectionID string
+}
+
+func (q *Queries) UpdateConnectionState(ctx context.Context, arg UpdateConnectionStateParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateConnectionState,
+		arg.State,
+		arg.TheirLabel,
+		arg.TheirDid,
+		arg.Role,
+		arg.ConnectionID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const updateCredentialExchangeState = `-- name: UpdateCredentialExchangeState :execrows
+UPDATE credential_exchanges
+SET state = $1,
+    thread_id = COALESCE(NULLIF($2::text, ''), thread_id),
+    updated_at = now()
+WHERE cred_ex_id = $3
+`
+
+type UpdateCredentialExchangeStateParams struct {
+	State    string
+	ThreadID string
+	CredExID string
+}
+
+func (q *Queries) UpdateCredentialExchangeState(ctx context.Context, arg UpdateCredentialExchangeStateParams) (int64, error) {
+	result, err := q.db.Exec(ctx, updateCredentialExchangeState, arg.State, arg.ThreadID, arg.CredExID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+const upsertCredentialExchange = `-- name: UpsertCredentialExchange :exec
+INSERT INTO credential_exchanges (cred_ex_id, role, user_id, connection_id, counterparty_email, thread_id, schema_id, cred_def_id, attributes, attributes_encrypted, state)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+ON CONFLICT (cred_ex_id) DO UPDATE
+SET state = EXCLUDED.state,
+    thread_id = COALESCE(NULLIF(EXCLUDED.thread_id, ''), credential_exchanges.thread_id),
+    updated_at = now()
+`
+
+type UpsertCredentialExchangeParams struct {
+	CredExID            string
+	Role                string
+	UserID              int64
+	ConnectionID        string
+	CounterpartyEmail   string
+	ThreadID            string
+	SchemaID            string
+	CredDefID           string
+	Attributes          []byte
+	AttributesEncrypted bool
+	State               string
+}
+
+// Webhooks can arrive before the issuer has recorded the exchange it started,
+// the state they carry is the newest one
+func (q *Queries) UpsertCredentialExchange(ctx context.Context, arg UpsertCredentialExchangeParams) error {
+	_, err := q.db.Exec(ctx, upsertCredentialExchange,
+		arg.CredExID,
+		arg.Role,
+		arg.UserID,
+		arg.ConnectionID,
+		arg.CounterpartyEmail,
+		arg.ThreadID,
+		arg.SchemaID,
+		arg.CredDefID,
+		arg.Attributes,
+		arg.AttributesEncrypted,
+		arg.State,
+	)
+	return err
+}
@@ -0,0 +1,192 @@
+package emails
+
+import (
+	"bytes"
+	"digiauth/pkg/main-app/config"
+	"digiauth/pkg/main-app/notify"
+	"digiauth/pkg/main-app/qrcode"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Each email is made of three templates: <name>.subject.tmpl and
+// <name>.txt.tmpl rendered with text/template and <name>.html.tmpl rendered
+// with html/template. A file of the same name in the configured template
+// directory replaces the embedded one.
+//
+//go:embed templates/*.tmpl
+var embedded embed.FS
+
+const invitationEmail = "invitation"
+
+// invitationQRCodeID is the Content-ID of the QR code attached to invitations
+const invitationQRCodeID = "invitation-qr"
+
+type email struct {
+	subject *texttemplate.Template
+	text    *texttemplate.Template
+	html    *htmltemplate.Template
+}
+
+// Renderer turns email data into multipart plain text and HTML messages
+type Renderer struct {
+	brand     config.Brand
+	acceptURL string
+	emails    map[string]email
+}
+
+// New parses every email template, preferring the overrides in cfg.TemplateDir
+func New(cfg config.Emails) (*Renderer, error) {
+	r := &Renderer{brand: cfg.Brand, acceptURL: cfg.AcceptURL, emails: map[string]email{}}
+	for _, name := range []string{invitationEmail} {
+		var e email
+		var err error
+		if e.subject, err = parseText(cfg.TemplateDir, name+".subject.tmpl"); err != nil {
+			return nil, err
+		}
+		if e.text, err = parseText(cfg.TemplateDir, name+".txt.tmpl"); err != nil {
+			return nil, err
+		}
+		if e.html, err = parseHTML(cfg.TemplateDir, name+".html.tmpl"); err != nil {
+			return nil, err
+		}
+		r.emails[name] = e
+	}
+	return r, nil
+}
+
+// Invitation is the data available to the invitation templates
+type Invitation struct {
+	Brand        config.Brand
+	Role         string
+	RoleArticle  string
+	InviterEmail string
+	// AcceptURL carries the magic link token, when there is one, on top of
+	// the invitation itself
+	AcceptURL  string
+	Invitation string
+	ExpiresAt  time.Time
+	// QRCode is the inline image of AcceptURL, empty when it could not be made
+	QRCode htmltemplate.URL
+}
+
+// Invitation renders the email asking to to accept a connection invitation
+// sent by inviterEmail from the given role's agent. A non-empty token is added
+// to the accept link so that opening it while logged in accepts the
+// invitation without pasting it anywhere.
+func (r *Renderer) Invitation(to, role, inviterEmail string, invitation json.RawMessage, expiresAt time.Time, token string) (notify.Message, error) {
+	raw, err := json.MarshalIndent(invitation, "", "  ")
+	if err != nil {
+		return notify.Message{}, err
+	}
+	acceptURL, err := InvitationURL(r.acceptURL, invitation, inviterEmail)
+	if err != nil {
+		return notify.Message{}, fmt.Errorf("emails: build invitation link: %w", err)
+	}
+	magicLink, err := MagicLinkURL(acceptURL, token)
+	if err != nil {
+		return notify.Message{}, fmt.Errorf("emails: build invitation link: %w", err)
+	}
+
+	data := Invitation{
+		Brand:        r.brand,
+		Role:         role,
+		RoleArticle:  "a",
+		InviterEmail: inviterEmail,
+		AcceptURL:    magicLink,
+		Invitation:   string(raw),
+		ExpiresAt:    expiresAt.UTC(),
+	}
+	if role != "" && strings.ContainsAny(role[:1], "aeiou") {
+		data.RoleArticle = "an"
+	}
+
+	// Wallets scan the link without the token, which is of no use to them and
+	// only makes the code denser. A link too long to encode still leaves the
+	// email usable, so it goes out without the image.
+	var attachments []notify.Attachment
+	if png, err := qrcode.PNG(acceptURL, qrcode.DefaultSize); err == nil {
+		data.QRCode = htmltemplate.URL("cid:" + invitationQRCodeID)
+		attachments = append(attachments, notify.Attachment{
+			Filename:    "invitation.png",
+			ContentType: qrcode.PNGContentType,
+			ContentID:   invitationQRCodeID,
+			Data:        png,
+		})
+	}
+
+	msg, err := r.render(invitationEmail, to, data)
+	msg.Attachments = attachments
+	return msg, err
+}
+
+func (r *Renderer) render(name, to string, data interface{}) (notify.Message, error) {
+	e := r.emails[name]
+	var subject, text, html bytes.Buffer
+	if err := e.subject.Execute(&subject, data); err != nil {
+		return notify.Message{}, fmt.Errorf("emails: render %s subject: %w", name, err)
+	}
+	if err := e.text.Execute(&text, data); err != nil {
+		return notify.Message{}, fmt.Errorf("emails: render %s text: %w", name, err)
+	}
+	if err := e.html.Execute(&html, data); err != nil {
+		return notify.Message{}, fmt.Errorf("emails: render %s html: %w", name, err)
+	}
+	return notify.Message{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+func parseText(dir, file string) (*texttemplate.Template, error) {
+	body, err := read(dir, file)
+	if err != nil {
+		return nil, err
+	}
+	t, err := texttemplate.New(file).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("emails: parse %s: %w", file, err)
+	}
+	return t, nil
+}
+
+func parseHTML(dir, file string) (*htmltemplate.Template, error) {
+	body, err := read(dir, file)
+	if err != nil {
+		return nil, err
+	}
+	t, err := htmltemplate.New(file).Option("missingkey=error").Parse(body)
+	if err != nil {
+		return nil, fmt.Errorf("emails: parse %s: %w", file, err)
+	}
+	return t, nil
+}
+
+// read returns the override from dir when there is one, else the embedded file
+func read(dir, file string) (string, error) {
+	if dir != "" {
+		body, err := os.ReadFile(filepath.Join(dir, file))
+		if err == nil {
+			return string(body), nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return "", fmt.Errorf("emails: read %s: %w", file, err)
+		}
+	}
+	body, err := embedded.ReadFile("templates/" + file)
+	if err != nil {
+		return "", fmt.Errorf("emails: read embedded %s: %w", file, err)
+	}
+	return string(body), nil
+}
@@ -0,0 +1,49 @@
+package emails
+
+import (
+	"bytes"
+	"digiauth/pkg/acapy"
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+)
+
+// InvitationURL builds the acceptance link for an invitation. The invitation
+// is carried base64url encoded in oob for out-of-band invitations (RFC 0434)
+// or c_i for legacy ones (RFC 0160), along with the inviter's email the
+// receiving side records the connection under.
+func InvitationURL(base string, invitation json.RawMessage, inviterEmail string) (string, error) {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, invitation); err != nil {
+		return "", err
+	}
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	param := "c_i"
+	if acapy.IsOutOfBand(invitation) {
+		param = "oob"
+	}
+	query := u.Query()
+	query.Set(param, base64.URLEncoding.EncodeToString(compact.Bytes()))
+	query.Set("their_mail_id", inviterEmail)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// MagicLinkURL adds the signed invitation token to an acceptance link. The
+// link is returned unchanged when there is no token.
+func MagicLinkURL(acceptURL, token string) (string, error) {
+	if token == "" {
+		return acceptURL, nil
+	}
+	u, err := url.Parse(acceptURL)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,82 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types pushed to the frontends
+const (
+	ConnectionEstablished = "connection-established"
+	PingResponded         = "ping-responded"
+	CredentialOffered     = "credential-offered"
+	CredentialIssued      = "credential-issued"
+	ProofRequested        = "proof-requested"
+	ProofVerified         = "proof-verified"
+	MessageReceived       = "message-received"
+)
+
+// subscriberBuffer is how many events a slow client may lag behind before
+// further events are dropped for it
+const subscriberBuffer = 32
+
+type Event struct {
+	Type         string    `json:"type"`
+	UserID       int64     `json:"-"`
+	ConnectionID string    `json:"connection_id"`
+	RecordID     string    `json:"record_id,omitempty"`
+	State        string    `json:"state"`
+	Time         time.Time `json:"time"`
+}
+
+// Broker fans events out to the streams opened by each user
+type Broker struct {
+	mu   sync.Mutex
+	subs map[int64]map[chan Event]struct{}
+}
+
+func NewBroker() *Broker {
+	return &Broker{subs: map[int64]map[chan Event]struct{}{}}
+}
+
+// Subscribe returns the channel receiving the user's events and a function
+// that must be called to release it
+func (b *Broker) Subscribe(userID int64) (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subs[userID] == nil {
+		b.subs[userID] = map[chan Event]struct{}{}
+	}
+	b.subs[userID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[userID][ch]; !ok {
+			return
+		}
+		delete(b.subs[userID], ch)
+		if len(b.subs[userID]) == 0 {
+			delete(b.subs, userID)
+		}
+		close(ch)
+	}
+}
+
+// Publish delivers the event to every stream of its user without blocking
+func (b *Broker) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subs[event.UserID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package exchanges
+
+import (
+	"context"
+	"digiauth/pkg/main-app/auth"
+	"digiauth/pkg/main-app/db"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"digiauth/pkg/main-app/exchanges"
+	models "digiauth/pkg/main-app/exchanges/models"
+	"digiauth/pkg/main-app/server"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// Page sizes of exchange listings
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+// Controller serves the caller's credential exchanges on this server
+type Controller struct {
+	role   string
+	store  *db.Store
+	sealer *exchanges.Sealer
+}
+
+func NewController(deps server.Dependencies) *Controller {
+	return &Controller{role: deps.Role, store: deps.Store, sealer: deps.Sealer}
+}
+
+// ListCredentialExchanges returns the caller's exchanges, newest first.
+// ?connection_id=, ?counterparty= (the other party's email) and ?state=
+// narrow them down, ?limit= and ?offset= page through them.
+func (c *Controller) ListCredentialExchanges(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	query := r.URL.Query()
+	limit := defaultLimit
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			http.Error(w, "limit must be between 1 and 200", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	offset := 0
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "offset must not be negative", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	rows, err := c.store.ListCredentialExchanges(ctx, sql.ListCredentialExchangesParams{
+		UserID:            user.ID,
+		Role:              c.role,
+		ConnectionID:      query.Get("connection_id"),
+		CounterpartyEmail: query.Get("counterparty"),
+		State:             query.Get("state"),
+		RowLimit:          int32(limit),
+		RowOffset:         int32(offset),
+	})
+	if err != nil {
+		log.Println("Error fetching credential exchanges from db : ", err.Error())
+		http.Error(w, "Error fetching credential exchanges from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	credentialExchanges := []models.CredentialExchange{}
+	for _, row := range rows {
+		credentialExchanges = append(credentialExchanges, toCredentialExchange(row))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"credential_exchanges": credentialExchanges})
+}
+
+// GetCredentialExchange returns one of the caller's exchanges with the
+// attribute values
+func (c *Controller) GetCredentialExchange(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	user, _ := auth.UserFromContext(r.Context())
+	row, err := c.store.GetCredentialExchange(ctx, mux.Vars(r)["cred_ex_id"])
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && (row.UserID != user.ID || row.Role != c.role)) {
+		http.Error(w, "Credential exchange not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Error fetching credential exchange from db : ", err.Error())
+		http.Error(w, "Error fetching credential exchange from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	credentialExchange := toCredentialExchange(row)
+	credentialExchange.Attributes, err = c.sealer.Open(row.Attributes, row.AttributesEncrypted)
+	if err != nil {
+		log.Println("Error opening credential attributes : ", err.Error())
+		http.Error(w, "Failed to read credential attributes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"credential_exchange": credentialExchange})
+}
+
+func toCredentialExchange(row sql.CredentialExchange) models.CredentialExchange {
+	return models.CredentialExchange{
+		CredExID:          row.CredExID,
+		Role:              row.Role,
+		ConnectionID:      row.ConnectionID,
+		CounterpartyEmail: row.CounterpartyEmail,
+		ThreadID:          row.ThreadID,
+		SchemaID:          row.SchemaID,
+		CredDefID:         row.CredDefID,
+		State:             row.State,
+		CreatedAt:         row.CreatedAt.Time,
+		UpdatedAt:         row.UpdatedAt.Time,
+	}
+}
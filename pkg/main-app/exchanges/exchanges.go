@@ -0,0 +1,83 @@
+package exchanges
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"digiauth/pkg/acapy"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+var ErrNoKey = errors.New("exchanges: attributes are encrypted and no key is configured")
+
+// Sealer stores credential attribute values, encrypted with AES-256-GCM
+// when a key is configured and as plain JSON otherwise. A nil Sealer stores
+// them in plain JSON.
+type Sealer struct {
+	aead cipher.AEAD
+}
+
+// NewSealer builds a sealer from a base64 encoded 32 byte key. An empty key
+// leaves attributes unencrypted.
+func NewSealer(key string) (*Sealer, error) {
+	if key == "" {
+		return &Sealer{}, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("exchanges: encryption key must be 32 bytes, base64 encoded")
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{aead: aead}, nil
+}
+
+// Seal returns the stored form of the attributes and whether it is encrypted
+func (s *Sealer) Seal(attributes []acapy.CredentialAttribute) ([]byte, bool, error) {
+	plain, err := json.Marshal(attributes)
+	if err != nil {
+		return nil, false, err
+	}
+	if s == nil || s.aead == nil {
+		return plain, false, nil
+	}
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, false, err
+	}
+	return s.aead.Seal(nonce, nonce, plain, nil), true, nil
+}
+
+// Open reverses Seal
+func (s *Sealer) Open(stored []byte, encrypted bool) ([]acapy.CredentialAttribute, error) {
+	if len(stored) == 0 {
+		return nil, nil
+	}
+	plain := stored
+	if encrypted {
+		if s == nil || s.aead == nil {
+			return nil, ErrNoKey
+		}
+		size := s.aead.NonceSize()
+		if len(stored) < size {
+			return nil, errors.New("exchanges: sealed attributes are truncated")
+		}
+		var err error
+		plain, err = s.aead.Open(nil, stored[:size], stored[size:], nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var attributes []acapy.CredentialAttribute
+	err := json.Unmarshal(plain, &attributes)
+	return attributes, err
+}
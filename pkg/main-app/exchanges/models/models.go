@@ -0,0 +1,23 @@
+package exchanges
+
+import (
+	"digiauth/pkg/acapy"
+	"time"
+)
+
+// CredentialExchange is the local record of a credential sent by an issuer
+// or offered to a holder. Attributes are only returned when inspecting one
+// exchange.
+type CredentialExchange struct {
+	CredExID          string                      `json:"cred_ex_id"`
+	Role              string                      `json:"role"`
+	ConnectionID      string                      `json:"connection_id"`
+	CounterpartyEmail string                      `json:"counterparty_email"`
+	ThreadID          string                      `json:"thread_id"`
+	SchemaID          string                      `json:"schema_id"`
+	CredDefID         string                      `json:"cred_def_id"`
+	Attributes        []acapy.CredentialAttribute `json:"attributes,omitempty"`
+	State             string                      `json:"state"`
+	CreatedAt         time.Time                   `json:"created_at"`
+	UpdatedAt         time.Time                   `json:"updated_at"`
+}
@@ -0,0 +1,17 @@
+package exchanges
+
+import (
+	controllers "digiauth/pkg/main-app/exchanges/controllers"
+	"digiauth/pkg/main-app/server"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes adds the credential exchange records of the role to its
+// protected router. Issuers see the credentials they sent, holders the ones
+// offered to them.
+func RegisterRoutes(protected *mux.Router, deps server.Dependencies) {
+	controller := controllers.NewController(deps)
+	protected.HandleFunc("/credential-exchanges", controller.ListCredentialExchanges).Methods("GET")
+	protected.HandleFunc("/credential-exchanges/{cred_ex_id}", controller.GetCredentialExchange).Methods("GET")
+}
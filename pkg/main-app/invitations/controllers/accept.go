@@ -0,0 +1,92 @@
+package invitations
+
+import (
+	"context"
+	"digiauth/pkg/main-app/auth"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	lifecycle "digiauth/pkg/main-app/invitations"
+	models "digiauth/pkg/main-app/invitations/models"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// AcceptInvitation receives the invitation behind an invitation email's magic
+// link on this server's agent and records the connection for the caller, who
+// must be logged in as the address the invitation was sent to. The link is
+// redeemed on the server of the role that was invited, never the inviter's.
+func (c *Controller) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var requestData models.AcceptInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || requestData.Token == "" {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+
+	// An expired token is an expired invitation, both share the same expiry
+	inviterConnectionID, email, err := c.tokens.ParseInvitation(requestData.Token)
+	if err != nil {
+		auth.WriteError(w, http.StatusBadRequest, "invalid_invitation_link", "This invitation link is invalid or has expired")
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	if !strings.EqualFold(user.Email, email) {
+		auth.WriteError(w, http.StatusForbidden, "forbidden", "This invitation was sent to another address")
+		return
+	}
+
+	invitation, err := c.store.GetInvitation(ctx, inviterConnectionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Error fetching invitation from db : ", err.Error())
+		http.Error(w, "Error fetching invitation from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	inviter, err := c.store.GetUserByID(ctx, invitation.UserID)
+	if err != nil {
+		log.Println("Error fetching inviter from db : ", err.Error())
+		http.Error(w, "Error fetching inviter from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The invitation is claimed before the agent receives it, the same way
+	// as one pasted into /receive-invitation
+	connectionID, responseData, err := lifecycle.ReceiveTracked(ctx, c.store, c.agent, c.role, invitation)
+	if err != nil {
+		lifecycle.WriteError(w, err)
+		return
+	}
+
+	err = c.store.CreateConnection(ctx, sql.CreateConnectionParams{
+		ConnectionID: connectionID,
+		ID:           user.ID,
+		MyMailID:     user.Email,
+		TheirMailID:  inviter.Email,
+		Role:         c.role,
+	})
+	if err != nil {
+		log.Println("Error inserting connection to db : ", err.Error())
+		http.Error(w, "Error inserting connection to db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":       "Invitation Accepted",
+		"connection_id": connectionID,
+		"their_mail_id": inviter.Email,
+		"connection":    responseData,
+	})
+}
@@ -0,0 +1,240 @@
+package invitations
+
+import (
+	"context"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/auth"
+	"digiauth/pkg/main-app/config"
+	"digiauth/pkg/main-app/db"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"digiauth/pkg/main-app/emails"
+	lifecycle "digiauth/pkg/main-app/invitations"
+	models "digiauth/pkg/main-app/invitations/models"
+	"digiauth/pkg/main-app/qrcode"
+	"digiauth/pkg/main-app/server"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// maxQRCodeSize bounds the PNG width callers may ask for
+const maxQRCodeSize = 1024
+
+// Store is the part of the shared store the invitation handlers use, which
+// *db.Store implements
+type Store interface {
+	lifecycle.Store
+	auth.ConnectionStore
+	GetUserByID(ctx context.Context, id int64) (sql.User, error)
+	CreateConnection(ctx context.Context, arg sql.CreateConnectionParams) error
+	ListInvitations(ctx context.Context, arg sql.ListInvitationsParams) ([]sql.Invitation, error)
+	RevokeInvitation(ctx context.Context, arg sql.RevokeInvitationParams) (int64, error)
+	CreatePublicInvitation(ctx context.Context, arg sql.CreatePublicInvitationParams) (sql.PublicInvitation, error)
+	GetPublicInvitation(ctx context.Context, id string) (sql.PublicInvitation, error)
+	ListPublicInvitations(ctx context.Context, arg sql.ListPublicInvitationsParams) ([]sql.PublicInvitation, error)
+	RevokePublicInvitation(ctx context.Context, arg sql.RevokePublicInvitationParams) (int64, error)
+}
+
+var _ Store = (*db.Store)(nil)
+
+// Controller serves the invitations the caller created on this server
+type Controller struct {
+	role   string
+	cfg    *config.Config
+	agent  acapy.Agent
+	store  Store
+	tokens *auth.TokenManager
+}
+
+func NewController(deps server.Dependencies) *Controller {
+	return &Controller{role: deps.Role, cfg: deps.Config, agent: deps.Agent, store: deps.Store, tokens: deps.Tokens}
+}
+
+// ListInvitations returns the caller's invitations, newest first. ?status=
+// narrows them to pending, accepted, revoked or expired ones.
+func (c *Controller) ListInvitations(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "", lifecycle.StatusPending, lifecycle.StatusAccepted, lifecycle.StatusRevoked, lifecycle.StatusExpired:
+	default:
+		http.Error(w, "status must be pending, accepted, revoked or expired", http.StatusBadRequest)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	rows, err := c.store.ListInvitations(ctx, sql.ListInvitationsParams{UserID: user.ID, Role: c.role})
+	if err != nil {
+		log.Println("Error fetching invitations from db : ", err.Error())
+		http.Error(w, "Error fetching invitations from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now()
+	invitations := []models.Invitation{}
+	for _, row := range rows {
+		invitation := toInvitation(row, now)
+		if status == "" || invitation.Status == status {
+			invitations = append(invitations, invitation)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"invitations": invitations})
+}
+
+// CancelInvitation revokes a pending or expired invitation and removes its
+// records from the agent so wallets can no longer accept it either
+func (c *Controller) CancelInvitation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	connectionID := mux.Vars(r)["connection_id"]
+	user, _ := auth.UserFromContext(r.Context())
+
+	invitation, err := c.store.GetInvitation(ctx, connectionID)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && (invitation.UserID != user.ID || invitation.Role != c.role)) {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Error fetching invitation from db : ", err.Error())
+		http.Error(w, "Error fetching invitation from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if status := lifecycle.Status(invitation, time.Now()); status != lifecycle.StatusPending && status != lifecycle.StatusExpired {
+		http.Error(w, "Only pending invitations can be cancelled", http.StatusConflict)
+		return
+	}
+
+	// Wallets outside DigiAuth accept invitations straight from the agent, so
+	// its records go first. Records the agent no longer has are already gone.
+	if acapy.IsOutOfBand(invitation.Invitation) && invitation.InvitationID != "" {
+		if err := c.agent.DeleteOOBInvitation(ctx, invitation.InvitationID); err != nil && !acapy.IsNotFound(err) {
+			log.Println("Failed to delete invitation from agent: ", err)
+			http.Error(w, "Failed to delete invitation from agent: "+err.Error(), acapy.StatusCode(err))
+			return
+		}
+	}
+	if err := c.agent.DeleteConnection(ctx, connectionID); err != nil && !acapy.IsNotFound(err) {
+		log.Println("Failed to delete connection of cancelled invitation: ", err)
+		http.Error(w, "Failed to delete connection of cancelled invitation: "+err.Error(), acapy.StatusCode(err))
+		return
+	}
+
+	revoked, err := c.store.RevokeInvitation(ctx, sql.RevokeInvitationParams{ConnectionID: connectionID, UserID: user.ID})
+	if err != nil {
+		log.Println("Error revoking invitation : ", err.Error())
+		http.Error(w, "Error revoking invitation : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if revoked == 0 {
+		http.Error(w, "Only pending invitations can be cancelled", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "Invitation Cancelled"}`))
+}
+
+// QRCode renders the invitation of a connection as a QR code of the same
+// acceptance link the invitation email carries. It is a PNG unless
+// ?format=svg is given, ?size= sets the PNG width in pixels.
+func (c *Controller) QRCode(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	connectionID := mux.Vars(r)["connection_id"]
+	user, _ := auth.UserFromContext(r.Context())
+	if err := auth.AuthorizeConnection(ctx, c.store, user, c.role, connectionID); err != nil {
+		auth.WriteAuthorizationError(w, err)
+		return
+	}
+
+	invitation, err := c.store.GetInvitation(ctx, connectionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		http.Error(w, "No invitation was created for this connection", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Error fetching invitation from db : ", err.Error())
+		http.Error(w, "Error fetching invitation from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	acceptURL, err := emails.InvitationURL(c.cfg.Emails.AcceptURL, invitation.Invitation, user.Email)
+	if err != nil {
+		log.Println("Error building invitation link : ", err.Error())
+		http.Error(w, "Failed to build invitation link", http.StatusInternalServerError)
+		return
+	}
+	writeQRCode(w, r, acceptURL)
+}
+
+// writeQRCode answers with content encoded in the format and size asked for
+// in the query string
+func writeQRCode(w http.ResponseWriter, r *http.Request, content string) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "png"
+	}
+	if format != "png" && format != "svg" {
+		http.Error(w, "format must be png or svg", http.StatusBadRequest)
+		return
+	}
+	size := qrcode.DefaultSize
+	if v := r.URL.Query().Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 64 || n > maxQRCodeSize {
+			http.Error(w, "size must be between 64 and 1024", http.StatusBadRequest)
+			return
+		}
+		size = n
+	}
+
+	var image []byte
+	var err error
+	contentType := qrcode.PNGContentType
+	if format == "svg" {
+		image, err = qrcode.SVG(content)
+		contentType = qrcode.SVGContentType
+	} else {
+		image, err = qrcode.PNG(content, size)
+	}
+	if err != nil {
+		log.Println("Error encoding invitation QR code : ", err.Error())
+		http.Error(w, "Failed to encode invitation QR code", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	w.Write(image)
+}
+
+func toInvitation(row sql.Invitation, now time.Time) models.Invitation {
+	invitation := models.Invitation{
+		ConnectionID:   row.ConnectionID,
+		InvitationID:   row.InvitationID,
+		RecipientEmail: row.RecipientEmail,
+		Status:         lifecycle.Status(row, now),
+		CreatedAt:      row.CreatedAt.Time,
+		ExpiresAt:      row.ExpiresAt.Time,
+	}
+	if row.AcceptedAt.Valid {
+		invitation.AcceptedAt = &row.AcceptedAt.Time
+	}
+	if row.RevokedAt.Valid {
+		invitation.RevokedAt = &row.RevokedAt.Time
+	}
+	return invitation
+}
@@ -0,0 +1,338 @@
+package invitations
+
+import (
+	"context"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/acapy/acapytest"
+	"digiauth/pkg/main-app/auth"
+	"digiauth/pkg/main-app/config"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	lifecycle "digiauth/pkg/main-app/invitations"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const (
+	legacyInvitation = `{"@type": "https://didcomm.org/connections/1.0/invitation", "@id": "msg-1", "label": "Issuer", "recipientKeys": ["key"], "serviceEndpoint": "http://issuer"}`
+	oobInvitation    = `{"@type": "https://didcomm.org/out-of-band/1.1/invitation", "@id": "msg-1", "label": "Issuer", "services": ["did:sov:abc"]}`
+)
+
+var (
+	inviter = sql.User{ID: 1, Email: "issuer@example.com"}
+	invitee = sql.User{ID: 2, Email: "holder@example.com"}
+)
+
+// fakeStore keeps one user's invitations in memory. The embedded Store is
+// nil, so handlers calling anything else fail the test with a panic.
+type fakeStore struct {
+	Store
+	invitations map[string]*sql.Invitation
+	connections []sql.CreateConnectionParams
+	revoked     int
+}
+
+func newFakeStore(invitations ...*sql.Invitation) *fakeStore {
+	s := &fakeStore{invitations: map[string]*sql.Invitation{}}
+	for _, inv := range invitations {
+		s.invitations[inv.ConnectionID] = inv
+	}
+	return s
+}
+
+func (s *fakeStore) GetInvitation(ctx context.Context, connectionID string) (sql.Invitation, error) {
+	inv, ok := s.invitations[connectionID]
+	if !ok {
+		return sql.Invitation{}, pgx.ErrNoRows
+	}
+	return *inv, nil
+}
+
+func (s *fakeStore) GetInvitationByMessageID(ctx context.Context, invitationID string) (sql.Invitation, error) {
+	for _, inv := range s.invitations {
+		if inv.InvitationID == invitationID {
+			return *inv, nil
+		}
+	}
+	return sql.Invitation{}, pgx.ErrNoRows
+}
+
+func (s *fakeStore) GetPublicInvitationByMessageID(ctx context.Context, invitationMsgID string) (sql.PublicInvitation, error) {
+	return sql.PublicInvitation{}, pgx.ErrNoRows
+}
+
+func (s *fakeStore) AcceptInvitation(ctx context.Context, connectionID string) (int64, error) {
+	inv, ok := s.invitations[connectionID]
+	if !ok || inv.Status != lifecycle.StatusPending || !time.Now().Before(inv.ExpiresAt.Time) {
+		return 0, nil
+	}
+	inv.Status = lifecycle.StatusAccepted
+	return 1, nil
+}
+
+func (s *fakeStore) ReleaseInvitation(ctx context.Context, connectionID string) error {
+	if inv, ok := s.invitations[connectionID]; ok && inv.Status == lifecycle.StatusAccepted {
+		inv.Status = lifecycle.StatusPending
+	}
+	return nil
+}
+
+func (s *fakeStore) RevokeInvitation(ctx context.Context, arg sql.RevokeInvitationParams) (int64, error) {
+	inv, ok := s.invitations[arg.ConnectionID]
+	if !ok || inv.UserID != arg.UserID || inv.Status != lifecycle.StatusPending {
+		return 0, nil
+	}
+	inv.Status = lifecycle.StatusRevoked
+	s.revoked++
+	return 1, nil
+}
+
+func (s *fakeStore) GetUserByID(ctx context.Context, id int64) (sql.User, error) {
+	for _, user := range []sql.User{inviter, invitee} {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return sql.User{}, pgx.ErrNoRows
+}
+
+func (s *fakeStore) CreateConnection(ctx context.Context, arg sql.CreateConnectionParams) error {
+	s.connections = append(s.connections, arg)
+	return nil
+}
+
+func invitation(invitation, role, status string, expiresIn time.Duration) *sql.Invitation {
+	return &sql.Invitation{
+		ConnectionID:   "inviter-conn",
+		Role:           role,
+		Invitation:     []byte(invitation),
+		InvitationID:   "msg-1",
+		UserID:         inviter.ID,
+		RecipientEmail: invitee.Email,
+		Status:         status,
+		ExpiresAt:      pgtype.Timestamptz{Time: time.Now().Add(expiresIn), Valid: true},
+	}
+}
+
+func withUser(r *http.Request, user sql.User) *http.Request {
+	return r.WithContext(auth.WithUser(r.Context(), auth.User{ID: user.ID, Email: user.Email}))
+}
+
+func TestCancelInvitation(t *testing.T) {
+	notFound := func(ctx context.Context, id string) error { return acapytest.StatusError(http.StatusNotFound) }
+	deleted := func(ctx context.Context, id string) error { return nil }
+
+	tests := []struct {
+		name       string
+		invitation *sql.Invitation
+		user       sql.User
+		agent      *acapytest.Agent
+		wantHTTP   int
+		wantStatus string
+		wantCalls  []string
+	}{
+		{
+			name:       "pending legacy invitation",
+			invitation: invitation(legacyInvitation, config.RoleIssuer, lifecycle.StatusPending, time.Hour),
+			user:       inviter,
+			agent:      &acapytest.Agent{DeleteConnectionFunc: deleted},
+			wantHTTP:   http.StatusOK,
+			wantStatus: lifecycle.StatusRevoked,
+			wantCalls:  []string{"DeleteConnection"},
+		},
+		{
+			name:       "pending out-of-band invitation",
+			invitation: invitation(oobInvitation, config.RoleIssuer, lifecycle.StatusPending, time.Hour),
+			user:       inviter,
+			agent:      &acapytest.Agent{DeleteOOBInvitationFunc: deleted, DeleteConnectionFunc: deleted},
+			wantHTTP:   http.StatusOK,
+			wantStatus: lifecycle.StatusRevoked,
+			wantCalls:  []string{"DeleteOOBInvitation", "DeleteConnection"},
+		},
+		{
+			name:       "expired invitation the agent already forgot",
+			invitation: invitation(oobInvitation, config.RoleIssuer, lifecycle.StatusPending, -time.Minute),
+			user:       inviter,
+			agent:      &acapytest.Agent{DeleteOOBInvitationFunc: notFound, DeleteConnectionFunc: notFound},
+			wantHTTP:   http.StatusOK,
+			wantStatus: lifecycle.StatusRevoked,
+			wantCalls:  []string{"DeleteOOBInvitation", "DeleteConnection"},
+		},
+		{
+			name:       "agent failure keeps the invitation",
+			invitation: invitation(legacyInvitation, config.RoleIssuer, lifecycle.StatusPending, time.Hour),
+			user:       inviter,
+			agent: &acapytest.Agent{DeleteConnectionFunc: func(ctx context.Context, id string) error {
+				return acapytest.StatusError(http.StatusInternalServerError)
+			}},
+			wantHTTP:   http.StatusBadGateway,
+			wantStatus: lifecycle.StatusPending,
+			wantCalls:  []string{"DeleteConnection"},
+		},
+		{
+			name:       "accepted invitation",
+			invitation: invitation(legacyInvitation, config.RoleIssuer, lifecycle.StatusAccepted, time.Hour),
+			user:       inviter,
+			agent:      &acapytest.Agent{},
+			wantHTTP:   http.StatusConflict,
+			wantStatus: lifecycle.StatusAccepted,
+		},
+		{
+			name:       "another user's invitation",
+			invitation: invitation(legacyInvitation, config.RoleIssuer, lifecycle.StatusPending, time.Hour),
+			user:       invitee,
+			agent:      &acapytest.Agent{},
+			wantHTTP:   http.StatusNotFound,
+			wantStatus: lifecycle.StatusPending,
+		},
+		{
+			name:       "invitation of another role's server",
+			invitation: invitation(legacyInvitation, config.RoleVerifier, lifecycle.StatusPending, time.Hour),
+			user:       inviter,
+			agent:      &acapytest.Agent{},
+			wantHTTP:   http.StatusNotFound,
+			wantStatus: lifecycle.StatusPending,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(tt.invitation)
+			c := &Controller{role: config.RoleIssuer, agent: tt.agent, store: store}
+
+			req := httptest.NewRequest(http.MethodDelete, "/invitations/inviter-conn", nil)
+			req = mux.SetURLVars(withUser(req, tt.user), map[string]string{"connection_id": "inviter-conn"})
+			rec := httptest.NewRecorder()
+			c.CancelInvitation(rec, req)
+
+			if rec.Code != tt.wantHTTP {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantHTTP, rec.Body.String())
+			}
+			if tt.invitation.Status != tt.wantStatus {
+				t.Errorf("invitation status = %q, want %q", tt.invitation.Status, tt.wantStatus)
+			}
+			if calls := tt.agent.Calls(); !reflect.DeepEqual(calls, tt.wantCalls) && len(calls)+len(tt.wantCalls) > 0 {
+				t.Errorf("agent calls = %v, want %v", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestAcceptInvitation(t *testing.T) {
+	tokens := auth.NewTokenManager(config.Auth{Secret: "test-secret-with-enough-length", Issuer: "digiauth"})
+	receive := func(ctx context.Context, inv acapy.Invitation) (acapy.ConnRecord, error) {
+		return acapy.ConnRecord{ConnectionID: "invitee-conn"}, nil
+	}
+
+	tests := []struct {
+		name       string
+		invitation *sql.Invitation
+		role       string
+		user       sql.User
+		agent      *acapytest.Agent
+		wantHTTP   int
+		wantStatus string
+		wantRecv   int
+	}{
+		{
+			name:       "pending invitation",
+			invitation: invitation(legacyInvitation, config.RoleIssuer, lifecycle.StatusPending, time.Hour),
+			role:       config.RoleHolder,
+			user:       invitee,
+			agent:      &acapytest.Agent{ReceiveInvitationFunc: receive},
+			wantHTTP:   http.StatusOK,
+			wantStatus: lifecycle.StatusAccepted,
+			wantRecv:   1,
+		},
+		{
+			name:       "already accepted",
+			invitation: invitation(legacyInvitation, config.RoleIssuer, lifecycle.StatusAccepted, time.Hour),
+			role:       config.RoleHolder,
+			user:       invitee,
+			agent:      &acapytest.Agent{ReceiveInvitationFunc: receive},
+			wantHTTP:   http.StatusConflict,
+			wantStatus: lifecycle.StatusAccepted,
+		},
+		{
+			name:       "cancelled",
+			invitation: invitation(legacyInvitation, config.RoleIssuer, lifecycle.StatusRevoked, time.Hour),
+			role:       config.RoleHolder,
+			user:       invitee,
+			agent:      &acapytest.Agent{ReceiveInvitationFunc: receive},
+			wantHTTP:   http.StatusGone,
+			wantStatus: lifecycle.StatusRevoked,
+		},
+		{
+			name:       "opened on the inviter's server",
+			invitation: invitation(legacyInvitation, config.RoleIssuer, lifecycle.StatusPending, time.Hour),
+			role:       config.RoleIssuer,
+			user:       invitee,
+			agent:      &acapytest.Agent{ReceiveInvitationFunc: receive},
+			wantHTTP:   http.StatusConflict,
+			wantStatus: lifecycle.StatusPending,
+		},
+		{
+			name:       "sent to another address",
+			invitation: invitation(legacyInvitation, config.RoleIssuer, lifecycle.StatusPending, time.Hour),
+			role:       config.RoleHolder,
+			user:       inviter,
+			agent:      &acapytest.Agent{ReceiveInvitationFunc: receive},
+			wantHTTP:   http.StatusForbidden,
+			wantStatus: lifecycle.StatusPending,
+		},
+		{
+			name:       "agent failure releases the claim",
+			invitation: invitation(legacyInvitation, config.RoleIssuer, lifecycle.StatusPending, time.Hour),
+			role:       config.RoleHolder,
+			user:       invitee,
+			agent: &acapytest.Agent{ReceiveInvitationFunc: func(ctx context.Context, inv acapy.Invitation) (acapy.ConnRecord, error) {
+				return acapy.ConnRecord{}, acapytest.StatusError(http.StatusServiceUnavailable)
+			}},
+			wantHTTP:   http.StatusBadGateway,
+			wantStatus: lifecycle.StatusPending,
+			wantRecv:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newFakeStore(tt.invitation)
+			c := &Controller{role: tt.role, agent: tt.agent, store: store, tokens: tokens}
+
+			token, err := tokens.IssueInvitation(tt.invitation.ConnectionID, invitee.Email, tt.invitation.ExpiresAt.Time)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/invitations/accept", strings.NewReader(`{"token": "`+token+`"}`))
+			rec := httptest.NewRecorder()
+			c.AcceptInvitation(rec, withUser(req, tt.user))
+
+			if rec.Code != tt.wantHTTP {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantHTTP, rec.Body.String())
+			}
+			if tt.invitation.Status != tt.wantStatus {
+				t.Errorf("invitation status = %q, want %q", tt.invitation.Status, tt.wantStatus)
+			}
+			if got := tt.agent.Called("ReceiveInvitation"); got != tt.wantRecv {
+				t.Errorf("agent received the invitation %d times, want %d", got, tt.wantRecv)
+			}
+			wantConnections := 0
+			if tt.wantHTTP == http.StatusOK {
+				wantConnections = 1
+			}
+			if len(store.connections) != wantConnections {
+				t.Fatalf("created %d connections, want %d", len(store.connections), wantConnections)
+			}
+			if wantConnections == 1 && (store.connections[0].ID != invitee.ID || store.connections[0].Role != tt.role) {
+				t.Errorf("connection = %+v, want it owned by the invitee on the %s agent", store.connections[0], tt.role)
+			}
+		})
+	}
+}
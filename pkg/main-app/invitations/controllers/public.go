@@ -0,0 +1,227 @@
+package invitations
+
+import (
+	"context"
+	"crypto/rand"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/auth"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"digiauth/pkg/main-app/emails"
+	models "digiauth/pkg/main-app/invitations/models"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+)
+
+// CreatePublicInvitation creates a multi-use invitation for the caller to
+// publish. Every wallet accepting it gets its own connection, attributed to
+// the caller when the agent reports it.
+func (c *Controller) CreatePublicInvitation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var req models.CreatePublicInvitationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	user, _ := auth.UserFromContext(r.Context())
+
+	created, err := acapy.NewMultiUseInvitation(ctx, c.agent, c.cfg.Agents.InvitationProtocol)
+	if err != nil {
+		log.Println("Failed to create public invitation: ", err)
+		http.Error(w, "Failed to create public invitation: "+err.Error(), acapy.StatusCode(err))
+		return
+	}
+
+	id, err := newPublicID()
+	if err != nil {
+		log.Println("Error generating public invitation id : ", err.Error())
+		http.Error(w, "Failed to create public invitation", http.StatusInternalServerError)
+		return
+	}
+
+	invitation, err := c.store.CreatePublicInvitation(ctx, sql.CreatePublicInvitationParams{
+		ID:              id,
+		UserID:          user.ID,
+		Role:            c.role,
+		Label:           strings.TrimSpace(req.Label),
+		ConnectionID:    created.ConnectionID,
+		Invitation:      created.Invitation,
+		InvitationMsgID: created.InvitationMsgID,
+		InvitationKey:   created.InvitationKey,
+	})
+	if err != nil {
+		log.Println("Error inserting public invitation to db : ", err.Error())
+		http.Error(w, "Error inserting public invitation to db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	response, err := c.toPublicInvitation(invitation, user.Email)
+	if err != nil {
+		log.Println("Error building invitation link : ", err.Error())
+		http.Error(w, "Failed to build invitation link", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"invitation": response})
+}
+
+func (c *Controller) ListPublicInvitations(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	user, _ := auth.UserFromContext(r.Context())
+	rows, err := c.store.ListPublicInvitations(ctx, sql.ListPublicInvitationsParams{UserID: user.ID, Role: c.role})
+	if err != nil {
+		log.Println("Error fetching public invitations from db : ", err.Error())
+		http.Error(w, "Error fetching public invitations from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	invitations := []models.PublicInvitation{}
+	for _, row := range rows {
+		invitation, err := c.toPublicInvitation(row, user.Email)
+		if err != nil {
+			log.Println("Error building invitation link : ", err.Error())
+			http.Error(w, "Failed to build invitation link", http.StatusInternalServerError)
+			return
+		}
+		invitations = append(invitations, invitation)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"invitations": invitations})
+}
+
+// RevokePublicInvitation stops a public invitation from being accepted by
+// removing its records from the agent, then marks it revoked. Connections
+// already made with it are kept.
+func (c *Controller) RevokePublicInvitation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	id := mux.Vars(r)["id"]
+	user, _ := auth.UserFromContext(r.Context())
+
+	invitation, err := c.store.GetPublicInvitation(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && (invitation.UserID != user.ID || invitation.Role != c.role)) {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Error fetching public invitation from db : ", err.Error())
+		http.Error(w, "Error fetching public invitation from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if invitation.RevokedAt.Valid {
+		http.Error(w, "Invitation was already revoked", http.StatusConflict)
+		return
+	}
+
+	// Wallets outside DigiAuth accept the invitation straight from the agent,
+	// so its out-of-band record and template connection go first. Records
+	// the agent no longer has are already gone.
+	if acapy.IsOutOfBand(invitation.Invitation) && invitation.InvitationMsgID != "" {
+		if err := c.agent.DeleteOOBInvitation(ctx, invitation.InvitationMsgID); err != nil && !acapy.IsNotFound(err) {
+			log.Println("Failed to delete public invitation from agent: ", err)
+			http.Error(w, "Failed to delete public invitation from agent: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+	if err := c.agent.DeleteConnection(ctx, invitation.ConnectionID); err != nil && !acapy.IsNotFound(err) {
+		log.Println("Failed to delete connection of revoked public invitation: ", err)
+		http.Error(w, "Failed to delete connection of revoked public invitation: "+err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	revoked, err := c.store.RevokePublicInvitation(ctx, sql.RevokePublicInvitationParams{ID: id, UserID: user.ID})
+	if err != nil {
+		log.Println("Error revoking public invitation : ", err.Error())
+		http.Error(w, "Error revoking public invitation : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if revoked == 0 {
+		http.Error(w, "Invitation was already revoked", http.StatusConflict)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{"message": "Invitation Revoked"}`))
+}
+
+// ShowPublicInvitation is the unauthenticated page behind a published
+// invitation. It answers with the invitation as JSON, or as a QR code when
+// ?format=png or ?format=svg is given.
+func (c *Controller) ShowPublicInvitation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	invitation, err := c.store.GetPublicInvitation(ctx, mux.Vars(r)["id"])
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && (invitation.Role != c.role || invitation.RevokedAt.Valid)) {
+		http.Error(w, "Invitation not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Error fetching public invitation from db : ", err.Error())
+		http.Error(w, "Error fetching public invitation from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	owner, err := c.store.GetUserByID(ctx, invitation.UserID)
+	if err != nil {
+		log.Println("Error fetching user from db : ", err.Error())
+		http.Error(w, "Error fetching user from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	response, err := c.toPublicInvitation(invitation, owner.Email)
+	if err != nil {
+		log.Println("Error building invitation link : ", err.Error())
+		http.Error(w, "Failed to build invitation link", http.StatusInternalServerError)
+		return
+	}
+
+	if r.URL.Query().Get("format") != "" {
+		writeQRCode(w, r, response.URL)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"invitation": response})
+}
+
+func (c *Controller) toPublicInvitation(row sql.PublicInvitation, ownerEmail string) (models.PublicInvitation, error) {
+	url, err := emails.InvitationURL(c.cfg.Emails.AcceptURL, row.Invitation, ownerEmail)
+	if err != nil {
+		return models.PublicInvitation{}, err
+	}
+	invitation := models.PublicInvitation{
+		ID:         row.ID,
+		Label:      row.Label,
+		URL:        url,
+		Invitation: row.Invitation,
+		CreatedAt:  row.CreatedAt.Time,
+	}
+	if row.RevokedAt.Valid {
+		invitation.RevokedAt = &row.RevokedAt.Time
+	}
+	return invitation, nil
+}
+
+// newPublicID returns a random identifier that is safe to put in URLs
+func newPublicID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,186 @@
+package invitations
+
+import (
+	"context"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/auth"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Invitation statuses. Expired is never stored, it is a pending invitation
+// past its expiry.
+const (
+	StatusPending  = "pending"
+	StatusAccepted = "accepted"
+	StatusRevoked  = "revoked"
+	StatusExpired  = "expired"
+)
+
+var (
+	ErrExpired  = errors.New("invitation has expired")
+	ErrRevoked  = errors.New("invitation was revoked")
+	ErrAccepted = errors.New("invitation was already accepted")
+	// ErrWrongServer is an invitation received by the server that created
+	// it, whose agent would end up connected to itself
+	ErrWrongServer = errors.New("invitation was created by this server")
+)
+
+// Status returns the status of inv at now
+func Status(inv sql.Invitation, now time.Time) string {
+	if inv.Status == StatusPending && !now.Before(inv.ExpiresAt.Time) {
+		return StatusExpired
+	}
+	return inv.Status
+}
+
+// Store is the lookup and claim needed to receive an invitation
+type Store interface {
+	GetInvitation(ctx context.Context, connectionID string) (sql.Invitation, error)
+	GetInvitationByMessageID(ctx context.Context, invitationID string) (sql.Invitation, error)
+	GetPublicInvitationByMessageID(ctx context.Context, invitationMsgID string) (sql.PublicInvitation, error)
+	AcceptInvitation(ctx context.Context, connectionID string) (int64, error)
+	ReleaseInvitation(ctx context.Context, connectionID string) error
+}
+
+// receiveError wraps the agent's failure to receive an invitation
+type receiveError struct {
+	err error
+}
+
+func (e *receiveError) Error() string {
+	return e.err.Error()
+}
+
+func (e *receiveError) Unwrap() error {
+	return e.err
+}
+
+// Receive has agent, the agent of the role server, receive invitation.
+// Invitations created by these servers that expired, were revoked, were
+// already accepted or were created by the role server itself are refused. Single-use
+// ones are claimed before the agent sees them, so concurrent callers and
+// cancellations cannot both get through, and released again if the agent
+// fails. Public invitations stay open and invitations from other agents are
+// not tracked. It returns the connection created and the agent's record.
+func Receive(ctx context.Context, store Store, agent acapy.Agent, role string, invitation json.RawMessage) (string, interface{}, error) {
+	invitationID := acapy.InvitationID(invitation)
+	if invitationID != "" {
+		inv, err := store.GetInvitationByMessageID(ctx, invitationID)
+		if err == nil {
+			return ReceiveTracked(ctx, store, agent, role, inv)
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return "", nil, err
+		}
+		if err := checkPublic(ctx, store, role, invitationID); err != nil {
+			return "", nil, err
+		}
+	}
+	return accept(ctx, agent, invitation)
+}
+
+// ReceiveTracked has agent receive the single-use invitation inv, see Receive
+func ReceiveTracked(ctx context.Context, store Store, agent acapy.Agent, role string, inv sql.Invitation) (string, interface{}, error) {
+	if err := statusError(inv); err != nil {
+		return "", nil, err
+	}
+	if inv.Role == role {
+		return "", nil, ErrWrongServer
+	}
+	claimed, err := store.AcceptInvitation(ctx, inv.ConnectionID)
+	if err != nil {
+		return "", nil, err
+	}
+	if claimed == 0 {
+		return "", nil, lostClaim(ctx, store, inv.ConnectionID)
+	}
+
+	connectionID, record, err := accept(ctx, agent, inv.Invitation)
+	if err != nil {
+		if releaseErr := store.ReleaseInvitation(ctx, inv.ConnectionID); releaseErr != nil {
+			log.Println("Error releasing invitation : ", releaseErr.Error())
+		}
+		return "", nil, err
+	}
+	return connectionID, record, nil
+}
+
+func accept(ctx context.Context, agent acapy.Agent, invitation json.RawMessage) (string, interface{}, error) {
+	// Out-of-band and legacy invitations are told apart by their @type
+	connectionID, record, err := acapy.AcceptInvitation(ctx, agent, invitation)
+	if err != nil {
+		return "", nil, &receiveError{err: err}
+	}
+	return connectionID, record, nil
+}
+
+// statusError returns why inv can no longer be received, if it cannot
+func statusError(inv sql.Invitation) error {
+	switch Status(inv, time.Now()) {
+	case StatusExpired:
+		return ErrExpired
+	case StatusRevoked:
+		return ErrRevoked
+	case StatusAccepted:
+		return ErrAccepted
+	}
+	return nil
+}
+
+// lostClaim explains a claim that matched no pending invitation, which was
+// accepted, cancelled or expired since it was read
+func lostClaim(ctx context.Context, store Store, connectionID string) error {
+	inv, err := store.GetInvitation(ctx, connectionID)
+	if err != nil {
+		return err
+	}
+	if err := statusError(inv); err != nil {
+		return err
+	}
+	return ErrAccepted
+}
+
+func checkPublic(ctx context.Context, store Store, role, invitationID string) error {
+	inv, err := store.GetPublicInvitationByMessageID(ctx, invitationID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if inv.RevokedAt.Valid {
+		return ErrRevoked
+	}
+	if inv.Role == role {
+		return ErrWrongServer
+	}
+	return nil
+}
+
+// WriteError answers a failed Receive
+func WriteError(w http.ResponseWriter, err error) {
+	var receiveErr *receiveError
+	switch {
+	case errors.As(err, &receiveErr):
+		log.Println("Failed to receive invitation: ", err)
+		http.Error(w, "Failed to receive invitation: "+err.Error(), acapy.StatusCode(err))
+	case errors.Is(err, ErrExpired):
+		auth.WriteError(w, http.StatusGone, "invitation_expired", "This invitation has expired")
+	case errors.Is(err, ErrRevoked):
+		auth.WriteError(w, http.StatusGone, "invitation_revoked", "This invitation was cancelled")
+	case errors.Is(err, ErrAccepted):
+		auth.WriteError(w, http.StatusConflict, "invitation_used", "This invitation was already accepted")
+	case errors.Is(err, ErrWrongServer):
+		auth.WriteError(w, http.StatusConflict, "wrong_server", "This invitation must be accepted on another server")
+	default:
+		log.Println("Error checking invitation : ", err.Error())
+		auth.WriteError(w, http.StatusInternalServerError, "internal_error", "Failed to check invitation")
+	}
+}
@@ -0,0 +1,272 @@
+package invitations
+
+import (
+	"context"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/acapy/acapytest"
+	"digiauth/pkg/main-app/config"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+const legacyInvitation = `{"@type": "https://didcomm.org/connections/1.0/invitation", "@id": "msg-1", "label": "Issuer", "recipientKeys": ["key"], "serviceEndpoint": "http://issuer"}`
+
+// fakeStore keeps invitations in memory. beforeClaim runs ahead of each
+// AcceptInvitation, to change the invitation as a concurrent request would.
+type fakeStore struct {
+	invitations map[string]*sql.Invitation
+	public      map[string]sql.PublicInvitation
+	beforeClaim func(inv *sql.Invitation)
+	released    int
+}
+
+func (s *fakeStore) GetInvitation(ctx context.Context, connectionID string) (sql.Invitation, error) {
+	inv, ok := s.invitations[connectionID]
+	if !ok {
+		return sql.Invitation{}, pgx.ErrNoRows
+	}
+	return *inv, nil
+}
+
+func (s *fakeStore) GetInvitationByMessageID(ctx context.Context, invitationID string) (sql.Invitation, error) {
+	for _, inv := range s.invitations {
+		if inv.InvitationID == invitationID {
+			return *inv, nil
+		}
+	}
+	return sql.Invitation{}, pgx.ErrNoRows
+}
+
+func (s *fakeStore) GetPublicInvitationByMessageID(ctx context.Context, invitationMsgID string) (sql.PublicInvitation, error) {
+	inv, ok := s.public[invitationMsgID]
+	if !ok {
+		return sql.PublicInvitation{}, pgx.ErrNoRows
+	}
+	return inv, nil
+}
+
+func (s *fakeStore) AcceptInvitation(ctx context.Context, connectionID string) (int64, error) {
+	inv, ok := s.invitations[connectionID]
+	if !ok {
+		return 0, nil
+	}
+	if s.beforeClaim != nil {
+		s.beforeClaim(inv)
+	}
+	if inv.Status != StatusPending || !time.Now().Before(inv.ExpiresAt.Time) {
+		return 0, nil
+	}
+	inv.Status = StatusAccepted
+	return 1, nil
+}
+
+func (s *fakeStore) ReleaseInvitation(ctx context.Context, connectionID string) error {
+	if inv, ok := s.invitations[connectionID]; ok && inv.Status == StatusAccepted {
+		inv.Status = StatusPending
+		s.released++
+	}
+	return nil
+}
+
+func pendingInvitation(role string, expiresIn time.Duration) *sql.Invitation {
+	return &sql.Invitation{
+		ConnectionID: "inviter-conn",
+		Role:         role,
+		Invitation:   []byte(legacyInvitation),
+		InvitationID: "msg-1",
+		UserID:       1,
+		Status:       StatusPending,
+		ExpiresAt:    pgtype.Timestamptz{Time: time.Now().Add(expiresIn), Valid: true},
+	}
+}
+
+func receivingAgent() *acapytest.Agent {
+	return &acapytest.Agent{
+		ReceiveInvitationFunc: func(ctx context.Context, invitation acapy.Invitation) (acapy.ConnRecord, error) {
+			return acapy.ConnRecord{ConnectionID: "invitee-conn"}, nil
+		},
+	}
+}
+
+func TestReceive(t *testing.T) {
+	tests := []struct {
+		name        string
+		invitation  *sql.Invitation
+		public      *sql.PublicInvitation
+		beforeClaim func(inv *sql.Invitation)
+		agent       *acapytest.Agent
+		wantErr     error
+		wantStatus  string
+		wantHTTP    int
+		wantRecv    int
+		wantRelease int
+	}{
+		{
+			name:       "pending invitation is claimed and received",
+			invitation: pendingInvitation(config.RoleIssuer, time.Hour),
+			agent:      receivingAgent(),
+			wantStatus: StatusAccepted,
+			wantRecv:   1,
+		},
+		{
+			name:     "untracked invitation is received",
+			agent:    receivingAgent(),
+			wantRecv: 1,
+		},
+		{
+			name:       "expired invitation is refused",
+			invitation: pendingInvitation(config.RoleIssuer, -time.Minute),
+			agent:      receivingAgent(),
+			wantErr:    ErrExpired,
+			wantStatus: StatusPending,
+			wantHTTP:   http.StatusGone,
+		},
+		{
+			name: "revoked invitation is refused",
+			invitation: func() *sql.Invitation {
+				inv := pendingInvitation(config.RoleIssuer, time.Hour)
+				inv.Status = StatusRevoked
+				return inv
+			}(),
+			agent:      receivingAgent(),
+			wantErr:    ErrRevoked,
+			wantStatus: StatusRevoked,
+			wantHTTP:   http.StatusGone,
+		},
+		{
+			name: "accepted invitation is refused",
+			invitation: func() *sql.Invitation {
+				inv := pendingInvitation(config.RoleIssuer, time.Hour)
+				inv.Status = StatusAccepted
+				return inv
+			}(),
+			agent:      receivingAgent(),
+			wantErr:    ErrAccepted,
+			wantStatus: StatusAccepted,
+			wantHTTP:   http.StatusConflict,
+		},
+		{
+			name:       "invitation from the same server is refused",
+			invitation: pendingInvitation(config.RoleHolder, time.Hour),
+			agent:      receivingAgent(),
+			wantErr:    ErrWrongServer,
+			wantStatus: StatusPending,
+			wantHTTP:   http.StatusConflict,
+		},
+		{
+			name:        "claim lost to a concurrent acceptance",
+			invitation:  pendingInvitation(config.RoleIssuer, time.Hour),
+			beforeClaim: func(inv *sql.Invitation) { inv.Status = StatusAccepted },
+			agent:       receivingAgent(),
+			wantErr:     ErrAccepted,
+			wantStatus:  StatusAccepted,
+			wantHTTP:    http.StatusConflict,
+		},
+		{
+			name:        "claim lost to a cancellation",
+			invitation:  pendingInvitation(config.RoleIssuer, time.Hour),
+			beforeClaim: func(inv *sql.Invitation) { inv.Status = StatusRevoked },
+			agent:       receivingAgent(),
+			wantErr:     ErrRevoked,
+			wantStatus:  StatusRevoked,
+			wantHTTP:    http.StatusGone,
+		},
+		{
+			name:       "claim is released when the agent fails",
+			invitation: pendingInvitation(config.RoleIssuer, time.Hour),
+			agent: &acapytest.Agent{
+				ReceiveInvitationFunc: func(ctx context.Context, invitation acapy.Invitation) (acapy.ConnRecord, error) {
+					return acapy.ConnRecord{}, acapytest.StatusError(http.StatusInternalServerError)
+				},
+			},
+			wantStatus:  StatusPending,
+			wantHTTP:    http.StatusBadGateway,
+			wantRecv:    1,
+			wantRelease: 1,
+		},
+		{
+			name:     "revoked public invitation is refused",
+			public:   &sql.PublicInvitation{InvitationMsgID: "msg-1", Role: config.RoleIssuer, RevokedAt: pgtype.Timestamptz{Time: time.Now(), Valid: true}},
+			agent:    receivingAgent(),
+			wantErr:  ErrRevoked,
+			wantHTTP: http.StatusGone,
+		},
+		{
+			name:     "public invitation is received",
+			public:   &sql.PublicInvitation{InvitationMsgID: "msg-1", Role: config.RoleIssuer},
+			agent:    receivingAgent(),
+			wantRecv: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &fakeStore{
+				invitations: map[string]*sql.Invitation{},
+				public:      map[string]sql.PublicInvitation{},
+				beforeClaim: tt.beforeClaim,
+			}
+			if tt.invitation != nil {
+				store.invitations[tt.invitation.ConnectionID] = tt.invitation
+			}
+			if tt.public != nil {
+				store.public[tt.public.InvitationMsgID] = *tt.public
+			}
+
+			connectionID, _, err := Receive(context.Background(), store, tt.agent, config.RoleHolder, json.RawMessage(legacyInvitation))
+			switch {
+			case tt.wantHTTP != 0:
+				if err == nil {
+					t.Fatal("Receive succeeded, want an error")
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("Receive error = %v, want %v", err, tt.wantErr)
+				}
+				rec := httptest.NewRecorder()
+				WriteError(rec, err)
+				if rec.Code != tt.wantHTTP {
+					t.Errorf("WriteError status = %d, want %d", rec.Code, tt.wantHTTP)
+				}
+			case err != nil:
+				t.Fatalf("Receive error = %v", err)
+			case connectionID != "invitee-conn":
+				t.Errorf("connection = %q, want invitee-conn", connectionID)
+			}
+
+			if got := tt.agent.Called("ReceiveInvitation"); got != tt.wantRecv {
+				t.Errorf("agent received the invitation %d times, want %d", got, tt.wantRecv)
+			}
+			if store.released != tt.wantRelease {
+				t.Errorf("released %d claims, want %d", store.released, tt.wantRelease)
+			}
+			if tt.invitation != nil && tt.invitation.Status != tt.wantStatus {
+				t.Errorf("invitation status = %q, want %q", tt.invitation.Status, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestReceiveConcurrentClaims(t *testing.T) {
+	store := &fakeStore{
+		invitations: map[string]*sql.Invitation{"inviter-conn": pendingInvitation(config.RoleIssuer, time.Hour)},
+	}
+	agent := receivingAgent()
+
+	if _, _, err := Receive(context.Background(), store, agent, config.RoleHolder, json.RawMessage(legacyInvitation)); err != nil {
+		t.Fatalf("first Receive error = %v", err)
+	}
+	if _, _, err := Receive(context.Background(), store, agent, config.RoleVerifier, json.RawMessage(legacyInvitation)); !errors.Is(err, ErrAccepted) {
+		t.Fatalf("second Receive error = %v, want %v", err, ErrAccepted)
+	}
+	if got := agent.Called("ReceiveInvitation"); got != 1 {
+		t.Errorf("agent received the invitation %d times, want 1", got)
+	}
+}
@@ -0,0 +1,37 @@
+package invitations
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type Invitation struct {
+	ConnectionID   string     `json:"connection_id"`
+	InvitationID   string     `json:"invitation_id"`
+	RecipientEmail string     `json:"recipient_email"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"created_at"`
+	ExpiresAt      time.Time  `json:"expires_at"`
+	AcceptedAt     *time.Time `json:"accepted_at,omitempty"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty"`
+}
+
+// AcceptInvitationRequest carries the token of an invitation email's link
+type AcceptInvitationRequest struct {
+	Token string `json:"token"`
+}
+
+type CreatePublicInvitationRequest struct {
+	Label string `json:"label"`
+}
+
+// PublicInvitation is a multi-use invitation. URL is the acceptance link to
+// publish, Invitation the message wallets accept.
+type PublicInvitation struct {
+	ID         string          `json:"id"`
+	Label      string          `json:"label"`
+	URL        string          `json:"url"`
+	Invitation json.RawMessage `json:"invitation"`
+	CreatedAt  time.Time       `json:"created_at"`
+	RevokedAt  *time.Time      `json:"revoked_at,omitempty"`
+}
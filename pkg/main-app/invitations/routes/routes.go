@@ -0,0 +1,27 @@
+package invitations
+
+import (
+	controllers "digiauth/pkg/main-app/invitations/controllers"
+	"digiauth/pkg/main-app/server"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes adds the invitation endpoints to a role's protected router
+func RegisterRoutes(protected *mux.Router, deps server.Dependencies) {
+	controller := controllers.NewController(deps)
+	protected.HandleFunc("/invitations", controller.ListInvitations).Methods("GET")
+	protected.HandleFunc("/invitations/accept", controller.AcceptInvitation).Methods("POST")
+	protected.HandleFunc("/invitations/{connection_id}", controller.CancelInvitation).Methods("DELETE")
+	protected.HandleFunc("/connections/{connection_id}/qr", controller.QRCode).Methods("GET")
+}
+
+// RegisterPublicRoutes adds the multi-use invitations a user can publish.
+// /public-invitations/{id} is open to anyone holding the link.
+func RegisterPublicRoutes(public, protected *mux.Router, deps server.Dependencies) {
+	controller := controllers.NewController(deps)
+	public.HandleFunc("/public-invitations/{id}", controller.ShowPublicInvitation).Methods("GET")
+	protected.HandleFunc("/public-invitations", controller.CreatePublicInvitation).Methods("POST")
+	protected.HandleFunc("/public-invitations", controller.ListPublicInvitations).Methods("GET")
+	protected.HandleFunc("/public-invitations/{id}", controller.RevokePublicInvitation).Methods("DELETE")
+}
@@ -3,18 +3,54 @@ package issuer
 import (
 	"bytes"
 	"context"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/auth"
+	"digiauth/pkg/main-app/config"
+	connstate "digiauth/pkg/main-app/connections"
 	"digiauth/pkg/main-app/db"
 	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"digiauth/pkg/main-app/emails"
+	"digiauth/pkg/main-app/exchanges"
+	"digiauth/pkg/main-app/invitations"
 	models "digiauth/pkg/main-app/issuer/models"
+	"digiauth/pkg/main-app/notify"
+	"digiauth/pkg/main-app/server"
 	"encoding/json"
-	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-func IssueCredential(w http.ResponseWriter, r *http.Request) {
+// Controller serves the HTTP handlers, talking to the agent configured for this
+// role and to the shared store
+type Controller struct {
+	cfg      *config.Config
+	agent    acapy.Agent
+	store    *db.Store
+	notifier notify.Notifier
+	emails   *emails.Renderer
+	tokens   *auth.TokenManager
+	sealer   *exchanges.Sealer
+}
+
+func NewController(deps server.Dependencies) *Controller {
+	return &Controller{
+		cfg:      deps.Config,
+		agent:    deps.Agent,
+		store:    deps.Store,
+		notifier: deps.Notifier,
+		emails:   deps.Emails,
+		tokens:   deps.Tokens,
+		sealer:   deps.Sealer,
+	}
+}
+
+func (c *Controller) IssueCredential(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
 	var req models.IssueCredentialRequest
 	// Decode the request body into the req struct
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -22,82 +58,80 @@ func IssueCredential(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	user, _ := auth.UserFromContext(r.Context())
+	if err := auth.AuthorizeConnection(ctx, c.store, user, config.RoleIssuer, req.ConnectionID); err != nil {
+		auth.WriteAuthorizationError(w, err)
+		return
+	}
+
 	var did string = "V8ErVjLajTWdW5CH1jKPyt"
 
-	requestBody := models.CredentialIssuance{
+	requestBody := acapy.CredentialSendRequest{
 		ConnectionID: req.ConnectionID,
-		Filter: map[string]models.IndyFilter{
-			"indy": {
-				CredDefID: req.CredentialDefinitionId,
+		Filter: acapy.CredentialFilter{
+			Indy: acapy.IndyFilter{
+				CredDefID:       req.CredentialDefinitionId,
+				SchemaIssuerDID: did,
+				SchemaID:        req.SchemaId,
+				SchemaName:      req.SchemaName,
+				IssuerDID:       did,
 			},
 		},
-		CredentialPreview: models.CredentialPreview{
+		CredentialPreview: acapy.CredentialPreview{
 			Type:       "https://didcomm.org/issue-credential/2.0/credential-preview",
 			Attributes: req.Attributes,
 		},
-		SchemaIssuerDID: did,
-		SchemaID:        req.SchemaId,
-		SchemaName:      req.SchemaName,
-		IssuerDID:       did,
 	}
 
-	log.Println(requestBody)
-	// Convert the req struct to JSON for the external request
-	ledgerRequest, err := json.Marshal(requestBody)
+	credentialExchange, err := c.agent.SendCredential(ctx, requestBody)
 	if err != nil {
-		http.Error(w, "Failed to marshal request", http.StatusInternalServerError)
+		log.Println("Failed to send credential: ", err)
+		http.Error(w, "Failed to send credential: "+err.Error(), acapy.StatusCode(err))
 		return
 	}
 
-	resp, err := http.Post("http://localhost:8041/issue-credential-2.0/send", "application/json", bytes.NewBuffer(ledgerRequest))
-	if err != nil {
-		http.Error(w, "Failed to contact external service", http.StatusInternalServerError)
-		return
+	// The credential is on its way, failing to record it must not report
+	// the issuance as failed
+	if err := c.recordCredentialExchange(ctx, user, req, credentialExchange); err != nil {
+		log.Println("Error recording credential exchange : ", err.Error())
 	}
-	defer resp.Body.Close()
 
-	log.Println(resp)
-	// Read the response from the external service
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		http.Error(w, "Failed to read response", http.StatusInternalServerError)
-		return
-	}
-	log.Println(body)
-	// Return the response from the external service to the original caller
+	// Return the response from the agent to the original caller
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(body)
+	json.NewEncoder(w).Encode(credentialExchange)
 }
 
-func GetConnections(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) GetConnections(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
-	var req models.GetConnectionsRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	user, _ := auth.UserFromContext(r.Context())
+
+	// The query string filters, sorts and pages the connections of this
+	// server's agent
+	params, limit, err := connstate.ListParams(r, user.ID, config.RoleIssuer)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	queries := sql.New(db.DB)
-	connections, conerr := queries.GetConnectionsByUserID(ctx, req.Id)
+	connections, conerr := c.store.ListConnections(ctx, params)
 	if conerr != nil {
 		log.Println("Error inserting connection to db : ", conerr.Error())
 		http.Error(w, "Error inserting connection to db : "+conerr.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	connections, nextCursor := connstate.Page(connections, limit)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"connections": connections})
+	json.NewEncoder(w).Encode(map[string]interface{}{"connections": connections, "next_cursor": nextCursor})
 }
 
-func GetSchemasDB(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) GetSchemasDB(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
 	// Define the request body model (for SchemaIdDB)
 	var req models.SchemaIdDB
-	// Initialize the SQL queries struct
-	queries := sql.New(db.DB)
 
 	// Decode the request body into SchemaIdDB
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -106,7 +140,7 @@ func GetSchemasDB(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Fetch the schema by ID from the database using GetSchemaById
-	res, err := queries.GetSchemaById(ctx, req.Id)
+	res, err := c.store.GetSchemaById(ctx, req.Id)
 	if err != nil {
 		log.Println("Error fetching schema from db:", err.Error())
 		http.Error(w, "Error fetching schema from db: "+err.Error(), http.StatusInternalServerError)
@@ -118,50 +152,32 @@ func GetSchemasDB(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"schema": res})
 }
 
-func ReceiveInvitation(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) ReceiveInvitation(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 	var requestData models.ReceiveInvitationRequest
 	//Decode the request body into req struct
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || len(requestData.Invitation) == 0 {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
-	// Convert the req struct to JSON for the external request
-	requestBody, err := json.Marshal(requestData.Invitation)
-	if err != nil {
-		http.Error(w, "Failed to marshal request", http.StatusInternalServerError)
-		return
-	}
-
-	resp, err := http.Post("http://localhost:6041/connections/receive-invitation", "application/json", bytes.NewBuffer(requestBody))
-	if err != nil {
-		http.Error(w, "Failed to contact external service", http.StatusInternalServerError)
-		return
-	}
-	defer resp.Body.Close()
-
-	// Read the response from the external service
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		http.Error(w, "Failed to read response", http.StatusInternalServerError)
-		return
-	}
+	user, _ := auth.UserFromContext(r.Context())
 
-	var responseData models.ResponseReceiveInvitation
-	err = json.Unmarshal(body, &responseData)
+	// Invitations created by these servers are checked and claimed before
+	// the agent receives them
+	connectionID, responseData, err := invitations.Receive(ctx, c.store, c.agent, config.RoleIssuer, requestData.Invitation)
 	if err != nil {
-		http.Error(w, "Failed to parse response", http.StatusInternalServerError)
+		invitations.WriteError(w, err)
 		return
 	}
 	log.Println("response data for receiving: ", responseData)
-	queries := sql.New(db.DB)
-	insertDBErr := queries.CreateConnection(ctx, sql.CreateConnectionParams{
-		ConnectionID: responseData.ConnectionID,
-		ID:           requestData.UserID,
-		MyMailID:     requestData.MyMailId,
+	insertDBErr := c.store.CreateConnection(ctx, sql.CreateConnectionParams{
+		ConnectionID: connectionID,
+		ID:           user.ID,
+		MyMailID:     user.Email,
 		TheirMailID:  requestData.TheirMailId,
+		Role:         config.RoleIssuer,
 	})
 	if insertDBErr != nil {
 		log.Println("Error inserting connection to db : ", insertDBErr.Error())
@@ -170,13 +186,13 @@ func ReceiveInvitation(w http.ResponseWriter, r *http.Request) {
 
 	}
 
-	// Return the response from the external service to the original caller
+	// Return the response from the agent to the original caller
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(body)
+	json.NewEncoder(w).Encode(responseData)
 }
 
 // This is the function to create invitation for connection
-func CreateInvitation(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) CreateInvitation(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
@@ -192,42 +208,26 @@ func CreateInvitation(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("request data: ", requestData)
 
-	resp, err := http.Post("http://localhost:8041/connections/create-invitation", "application/json", bytes.NewBuffer([]byte{}))
-	if err != nil {
-		http.Error(w, "Failed to contact external service", http.StatusInternalServerError)
-		return
-	}
-	defer resp.Body.Close()
+	user, _ := auth.UserFromContext(r.Context())
 
-	// Read the response from the external service
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		http.Error(w, "Failed to read response", http.StatusInternalServerError)
+	if len(requestData.Attachments) > 0 && c.cfg.Agents.InvitationProtocol != acapy.ProtocolOutOfBand {
+		http.Error(w, "Attachments need out-of-band invitations", http.StatusBadRequest)
 		return
 	}
 
-	// Parse the JSON response
-	var responseData models.InvitationResponse
-
-	err = json.Unmarshal(body, &responseData)
+	responseData, err := acapy.NewInvitation(ctx, c.agent, c.cfg.Agents.InvitationProtocol, requestData.Attachments)
 	if err != nil {
-		http.Error(w, "Failed to parse response", http.StatusInternalServerError)
+		log.Println("Failed to create invitation: ", err)
+		http.Error(w, "Failed to create invitation: "+err.Error(), acapy.StatusCode(err))
 		return
 	}
 
-	// Print the JSON string
-	invitationString, marshalErr := json.Marshal(responseData.Invitation)
-	if marshalErr != nil {
-		fmt.Println("Error:", marshalErr)
-		return
-	}
-
-	queries := sql.New(db.DB)
-	insertDBErr := queries.CreateConnection(ctx, sql.CreateConnectionParams{
+	insertDBErr := c.store.CreateConnection(ctx, sql.CreateConnectionParams{
 		ConnectionID: responseData.ConnectionID,
-		ID:           requestData.Id,
-		MyMailID:     requestData.MyMailId,
+		ID:           user.ID,
+		MyMailID:     user.Email,
 		TheirMailID:  requestData.TheirMailId,
+		Role:         config.RoleIssuer,
 	})
 
 	if insertDBErr != nil {
@@ -236,119 +236,119 @@ func CreateInvitation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	emailBody := models.SendEmail{
-		Email: requestData.TheirMailId,
-		Message: models.Message{
-			Subject: "Invitation to connect",
-			Body:    "{<h5 style=\"margin:0;padding:0\">\"invitation\":" + string(invitationString) + ",<br/>\"their_mail_id\":\"" + requestData.MyMailId + "\"</h5>}",
-		},
-	}
-
-	emailPayload, err := json.Marshal(emailBody)
-	if err != nil {
-		fmt.Println("Error in Marshalling emailbody:", err)
-		return
-	}
-
-	_, mailErr := http.Post("https://q648rhgza1.execute-api.ap-south-1.amazonaws.com/prod", "application/json", bytes.NewBuffer(emailPayload))
-	if mailErr != nil {
-		log.Println("Failed to send email in create invitation")
-		http.Error(w, "Failed to send email", http.StatusInternalServerError)
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"message": "Invitation Sent Successfully"}`))
-}
-
-// This is a function that registers schema with ledger
-func RegisterSchema(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
-
-	var req models.RegisterSchemaRequest
-	// Decode the request body into the req struct
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		return
-	}
-
-	var Tag = req.SchemaName
-	log.Println(Tag)
-	log.Println("req: ", req)
-	// Convert the req struct to JSON for the external request
-	requestBody, err := json.Marshal(req)
+	// Track the invitation so it can expire, be cancelled and be shown again
+	// as a QR code
+	expiresAt := time.Now().Add(time.Duration(c.cfg.Invitations.TTL))
+	err = c.store.CreateInvitation(ctx, sql.CreateInvitationParams{
+		ConnectionID:   responseData.ConnectionID,
+		Role:           config.RoleIssuer,
+		Invitation:     responseData.Invitation,
+		InvitationID:   acapy.InvitationID(responseData.Invitation),
+		UserID:         user.ID,
+		RecipientEmail: requestData.TheirMailId,
+		ExpiresAt:      pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
 	if err != nil {
-		http.Error(w, "Failed to marshal request", http.StatusInternalServerError)
+		log.Println("Error inserting invitation to db : ", err.Error())
+		http.Error(w, "Error inserting invitation to db : "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// log.Println("req schema after marshal: ",requestBody)
-	registerSchemaResp, err := http.Post("http://localhost:8041/schemas", "application/json", bytes.NewBuffer(requestBody))
+	// The email's link accepts the invitation for a recipient who is logged in
+	token, err := c.tokens.IssueInvitation(responseData.ConnectionID, requestData.TheirMailId, expiresAt)
 	if err != nil {
-		http.Error(w, "Failed to contact external service", http.StatusInternalServerError)
+		log.Println("Error signing invitation link : ", err.Error())
+		http.Error(w, "Failed to prepare invitation email", http.StatusInternalServerError)
 		return
 	}
-	defer registerSchemaResp.Body.Close()
 
-	// Read the response from the external service
-	registerSchemaBody, err := io.ReadAll(registerSchemaResp.Body)
+	msg, err := c.emails.Invitation(requestData.TheirMailId, config.RoleIssuer, user.Email, responseData.Invitation, expiresAt, token)
 	if err != nil {
-		http.Error(w, "Failed to read response from register schema route", http.StatusInternalServerError)
+		log.Println("Error rendering invitation email : ", err.Error())
+		http.Error(w, "Failed to prepare invitation email", http.StatusInternalServerError)
 		return
 	}
 
-	log.Println("resp: ", registerSchemaResp)
-	var registerSchemaResponseData struct {
-		SchemaId string `json:"schema_id"`
-	}
-	err = json.Unmarshal(registerSchemaBody, &registerSchemaResponseData)
+	err = c.notifier.Send(ctx, msg)
 	if err != nil {
-		http.Error(w, "Failed to parse response", http.StatusInternalServerError)
+		log.Println("Failed to send email in create invitation: ", err)
+		http.Error(w, "Failed to send email", http.StatusBadGateway)
 		return
 	}
 
-	createCredentialDefinationRequestBody := map[string]interface{}{
-		"schema_id":                registerSchemaResponseData.SchemaId,
-		"tag":                      Tag,
-		"support-revocation":       true,
-		"revocation-registry-size": 1000,
-	}
+	// The connection ID lets the frontend show the invitation's QR code
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":       "Invitation Sent Successfully",
+		"connection_id": responseData.ConnectionID,
+	})
+}
 
-	// Marshal the map into a JSON byte slice
-	RequestBody, err := json.Marshal(createCredentialDefinationRequestBody)
+// recordCredentialExchange stores the credential sent to the holder so the
+// issuer can list and inspect its issuances
+func (c *Controller) recordCredentialExchange(ctx context.Context, user auth.User, req models.IssueCredentialRequest, exchange acapy.CredentialExchange) error {
+	connection, err := c.store.GetConnectionByID(ctx, req.ConnectionID)
 	if err != nil {
-		http.Error(w, "Failed to marshal JSON", http.StatusInternalServerError)
-		return
+		return err
 	}
-
-	createCredentialDefinationResp, err := http.Post("http://localhost:8041/credential-definitions", "application/json", bytes.NewBuffer(RequestBody))
+	attributes, encrypted, err := c.sealer.Seal(req.Attributes)
 	if err != nil {
-		http.Error(w, "Failed to contact external service", http.StatusInternalServerError)
+		return err
+	}
+	return c.store.CreateCredentialExchange(ctx, sql.CreateCredentialExchangeParams{
+		CredExID:            exchange.CredExID,
+		Role:                config.RoleIssuer,
+		UserID:              user.ID,
+		ConnectionID:        req.ConnectionID,
+		CounterpartyEmail:   connection.TheirMailID,
+		ThreadID:            exchange.ThreadID,
+		SchemaID:            req.SchemaId,
+		CredDefID:           req.CredentialDefinitionId,
+		Attributes:          attributes,
+		AttributesEncrypted: encrypted,
+		State:               exchange.State,
+	})
+}
+
+// This is a function that registers schema with ledger
+func (c *Controller) RegisterSchema(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var req models.RegisterSchemaRequest
+	// Decode the request body into the req struct
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
-	defer createCredentialDefinationResp.Body.Close()
 
-	createCredentialDefinationBody, err := io.ReadAll(createCredentialDefinationResp.Body)
+	var Tag = req.SchemaName
+	registerSchemaResponseData, err := c.agent.CreateSchema(ctx, acapy.SchemaSendRequest{
+		Attributes:    req.Attributes,
+		SchemaName:    req.SchemaName,
+		SchemaVersion: req.SchemaVersion,
+	})
 	if err != nil {
-		http.Error(w, "Failed to read response from create credential definition route", http.StatusInternalServerError)
+		log.Println("Failed to register schema: ", err)
+		http.Error(w, "Failed to register schema: "+err.Error(), acapy.StatusCode(err))
 		return
 	}
 
-	var createCredentialDefinationResponseData struct {
-		CrendentialDefinitionId string `json:"credential_definition_id"`
-	}
-	log.Println(createCredentialDefinationResponseData.CrendentialDefinitionId)
-
-	err = json.Unmarshal(createCredentialDefinationBody, &createCredentialDefinationResponseData)
+	createCredentialDefinationResponseData, err := c.agent.CreateCredentialDefinition(ctx, acapy.CredentialDefinitionSendRequest{
+		SchemaID:               registerSchemaResponseData.SchemaID,
+		Tag:                    Tag,
+		SupportRevocation:      true,
+		RevocationRegistrySize: 1000,
+	})
 	if err != nil {
-		http.Error(w, "Failed to parse response", http.StatusInternalServerError)
+		log.Println("Failed to create credential definition: ", err)
+		http.Error(w, "Failed to create credential definition: "+err.Error(), acapy.StatusCode(err))
 		return
 	}
 
-	queries := sql.New(db.DB)
-	insertDBErr := queries.CreateSchema(ctx, sql.CreateSchemaParams{
-		SchemaID:               registerSchemaResponseData.SchemaId,
-		CredentialDefinitionID: createCredentialDefinationResponseData.CrendentialDefinitionId,
+	insertDBErr := c.store.CreateSchema(ctx, sql.CreateSchemaParams{
+		SchemaID:               registerSchemaResponseData.SchemaID,
+		CredentialDefinitionID: createCredentialDefinationResponseData.CredentialDefinitionID,
 		SchemaName:             req.SchemaName,
 		Attributes:             req.Attributes,
 	})
@@ -365,7 +365,7 @@ func RegisterSchema(w http.ResponseWriter, r *http.Request) {
 }
 
 // This is the function for registering DID with Ledger
-func RegisterDID(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) RegisterDID(w http.ResponseWriter, r *http.Request) {
 	var req models.RegisterDIDRequest
 
 	// Decode the request body into the req struct
@@ -382,7 +382,7 @@ func RegisterDID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send the request to the external endpoint
-	resp, err := http.Post("http://test.bcovrin.vonx.io/register", "application/json", bytes.NewBuffer(requestBody))
+	resp, err := http.Post(c.cfg.LedgerURL, "application/json", bytes.NewBuffer(requestBody))
 	if err != nil {
 		http.Error(w, "Failed to contact external service", http.StatusInternalServerError)
 		return
@@ -401,44 +401,37 @@ func RegisterDID(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
-func GetSchemas(w http.ResponseWriter, r *http.Request) {
-	// Make the GET request to the external endpoint to fetch schemas
-	resp, err := http.Get("http://localhost:8041/schemas/created")
-	if err != nil {
-		http.Error(w, "Failed to fetch schemas from external service", http.StatusInternalServerError)
-		return
-	}
-	defer resp.Body.Close()
-
-	// Check if the response status is OK
-	if resp.StatusCode != http.StatusOK {
-		http.Error(w, "Failed to fetch schemas: unexpected status code", http.StatusInternalServerError)
-		return
-	}
-
-	// Read the response body
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		http.Error(w, "Failed to read response body", http.StatusInternalServerError)
-		return
-	}
-
-	// Define a struct to capture the schema IDs from the response
-	var schemasResponse struct {
-		SchemaIds []string `json:"schema_ids"`
-	}
+func (c *Controller) GetSchemas(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
 
-	// Parse the JSON response into the struct
-	err = json.Unmarshal(body, &schemasResponse)
+	// Fetch the IDs of the schemas created by the issuer agent
+	schemaIds, err := c.agent.ListCreatedSchemas(ctx)
 	if err != nil {
-		http.Error(w, "Failed to parse schemas response", http.StatusInternalServerError)
+		log.Println("Failed to fetch schemas: ", err)
+		http.Error(w, "Failed to fetch schemas: "+err.Error(), acapy.StatusCode(err))
 		return
 	}
 
 	// Log the received schema IDs for debugging
-	log.Printf("Fetched schema IDs: %v\n", schemasResponse.SchemaIds)
+	log.Printf("Fetched schema IDs: %v\n", schemaIds)
 
 	// Send the schema IDs back as a JSON response
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"schema_ids": schemasResponse.SchemaIds})
+	json.NewEncoder(w).Encode(map[string]interface{}{"schema_ids": schemaIds})
+}
+
+// Health reports whether the server can reach its database
+func (c *Controller) Health(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := c.store.Ping(ctx); err != nil {
+		log.Println("Health check failed: ", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status": "unavailable"}`))
+		return
+	}
+	w.Write([]byte(`{"status": "ok"}`))
 }
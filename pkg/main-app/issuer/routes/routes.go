@@ -1,20 +1,46 @@
 package issuer
 
 import (
+	account "digiauth/pkg/main-app/account/routes"
+	connections "digiauth/pkg/main-app/connections/routes"
+	exchanges "digiauth/pkg/main-app/exchanges/routes"
+	invitations "digiauth/pkg/main-app/invitations/routes"
 	controllers "digiauth/pkg/main-app/issuer/controllers"
+	messages "digiauth/pkg/main-app/messages/routes"
+	revocations "digiauth/pkg/main-app/revocations/routes"
+	"digiauth/pkg/main-app/server"
+	webhooks "digiauth/pkg/main-app/webhooks/routes"
+	"net/http"
 
 	"github.com/gorilla/mux"
 )
 
-func RegisterRoutes() *mux.Router {
+func RegisterRoutes(deps server.Dependencies) *mux.Router {
+	controller := controllers.NewController(deps)
 	r := mux.NewRouter()
-	r.HandleFunc("/register-certificate", controllers.RegisterSchema).Methods("POST")
-	r.HandleFunc("/register-did", controllers.RegisterDID).Methods("POST")
-	r.HandleFunc("/send-invitation", controllers.CreateInvitation).Methods("POST")
-	r.HandleFunc("/receive-invitation", controllers.ReceiveInvitation).Methods("POST")
-	r.HandleFunc("/connections", controllers.GetConnections).Methods("POST")
-	r.HandleFunc("/issue-credential", controllers.IssueCredential).Methods("POST")
-	r.HandleFunc("/created-schemas", controllers.GetSchemas).Methods("GET")
-	r.HandleFunc("/schemasGet", controllers.GetSchemasDB).Methods("POST")
+	r.HandleFunc("/health", controller.Health).Methods("GET")
+	webhooks.RegisterRoutes(r, deps)
+
+	// The event stream takes its token from the query string as well
+	r.Handle("/events", deps.Tokens.StreamMiddleware(http.HandlerFunc(deps.Events.Stream))).Methods("GET")
+
+	// Everything else needs an access token
+	api := r.NewRoute().Subrouter()
+	api.Use(deps.Tokens.Middleware)
+	api.HandleFunc("/register-certificate", controller.RegisterSchema).Methods("POST")
+	api.HandleFunc("/register-did", controller.RegisterDID).Methods("POST")
+	api.HandleFunc("/send-invitation", controller.CreateInvitation).Methods("POST")
+	api.HandleFunc("/receive-invitation", controller.ReceiveInvitation).Methods("POST")
+	api.HandleFunc("/connections", controller.GetConnections).Methods("GET", "POST")
+	api.HandleFunc("/issue-credential", controller.IssueCredential).Methods("POST")
+	api.HandleFunc("/created-schemas", controller.GetSchemas).Methods("GET")
+	api.HandleFunc("/schemasGet", controller.GetSchemasDB).Methods("POST")
+	connections.RegisterRoutes(api, deps)
+	exchanges.RegisterRoutes(api, deps)
+	invitations.RegisterRoutes(api, deps)
+	messages.RegisterRoutes(api, deps)
+	revocations.RegisterRoutes(api, deps)
+	invitations.RegisterPublicRoutes(r, api, deps)
+	account.RegisterRoutes(r, api, deps.Store, deps.Tokens)
 	return r
 }
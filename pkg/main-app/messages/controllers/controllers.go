@@ -0,0 +1,146 @@
+package messages
+
+import (
+	"context"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/auth"
+	"digiauth/pkg/main-app/db"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	basic "digiauth/pkg/main-app/messages"
+	models "digiauth/pkg/main-app/messages/models"
+	"digiauth/pkg/main-app/server"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Page sizes of message listings
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+// Controller sends and lists the basic messages of the caller's connections
+type Controller struct {
+	role  string
+	agent acapy.Agent
+	store *db.Store
+}
+
+func NewController(deps server.Dependencies) *Controller {
+	return &Controller{role: deps.Role, agent: deps.Agent, store: deps.Store}
+}
+
+// SendMessage sends a basic message over the connection and stores it
+func (c *Controller) SendMessage(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var requestData models.SendMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || strings.TrimSpace(requestData.Content) == "" {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	if len(requestData.Content) > basic.MaxContentLength {
+		http.Error(w, "content must be at most 10000 bytes", http.StatusBadRequest)
+		return
+	}
+
+	connectionID := mux.Vars(r)["connection_id"]
+	user, _ := auth.UserFromContext(r.Context())
+	if err := auth.AuthorizeConnection(ctx, c.store, user, c.role, connectionID); err != nil {
+		auth.WriteAuthorizationError(w, err)
+		return
+	}
+
+	err := c.agent.SendBasicMessage(ctx, connectionID, acapy.BasicMessageRequest{Content: requestData.Content})
+	if err != nil {
+		log.Println("Failed to send message: ", err)
+		http.Error(w, "Failed to send message: "+err.Error(), acapy.StatusCode(err))
+		return
+	}
+
+	message, err := c.store.CreateBasicMessage(ctx, sql.CreateBasicMessageParams{
+		ConnectionID: connectionID,
+		Direction:    basic.DirectionSent,
+		Content:      requestData.Content,
+		SentAt:       pgtype.Timestamptz{Time: time.Now(), Valid: true},
+	})
+	if err != nil {
+		log.Println("Error inserting message to db : ", err.Error())
+		http.Error(w, "Error inserting message to db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"message": toMessage(message)})
+}
+
+// ListMessages returns the messages of a connection, newest first. ?limit=
+// sets the page size and ?before= the id of the oldest message already seen.
+func (c *Controller) ListMessages(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	limit := defaultLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxLimit {
+			http.Error(w, "limit must be between 1 and 200", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	var before int64
+	if v := r.URL.Query().Get("before"); v != "" {
+		n, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || n < 1 {
+			http.Error(w, "before must be a message id", http.StatusBadRequest)
+			return
+		}
+		before = n
+	}
+
+	connectionID := mux.Vars(r)["connection_id"]
+	user, _ := auth.UserFromContext(r.Context())
+	if err := auth.AuthorizeConnection(ctx, c.store, user, c.role, connectionID); err != nil {
+		auth.WriteAuthorizationError(w, err)
+		return
+	}
+
+	rows, err := c.store.ListBasicMessages(ctx, sql.ListBasicMessagesParams{
+		ConnectionID: connectionID,
+		BeforeID:     before,
+		RowLimit:     int32(limit),
+	})
+	if err != nil {
+		log.Println("Error fetching messages from db : ", err.Error())
+		http.Error(w, "Error fetching messages from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	messages := []models.Message{}
+	for _, row := range rows {
+		messages = append(messages, toMessage(row))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"messages": messages})
+}
+
+func toMessage(row sql.BasicMessage) models.Message {
+	return models.Message{
+		ID:           row.ID,
+		ConnectionID: row.ConnectionID,
+		Direction:    row.Direction,
+		Content:      row.Content,
+		SentAt:       row.SentAt.Time,
+	}
+}
@@ -0,0 +1,11 @@
+package messages
+
+// Directions of stored basic messages, seen from the server's side of the
+// connection
+const (
+	DirectionSent     = "sent"
+	DirectionReceived = "received"
+)
+
+// MaxContentLength bounds the messages users may send, in bytes
+const MaxContentLength = 10000
@@ -0,0 +1,15 @@
+package messages
+
+import "time"
+
+type SendMessageRequest struct {
+	Content string `json:"content"`
+}
+
+type Message struct {
+	ID           int64     `json:"id"`
+	ConnectionID string    `json:"connection_id"`
+	Direction    string    `json:"direction"`
+	Content      string    `json:"content"`
+	SentAt       time.Time `json:"sent_at"`
+}
@@ -0,0 +1,15 @@
+package messages
+
+import (
+	controllers "digiauth/pkg/main-app/messages/controllers"
+	"digiauth/pkg/main-app/server"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes adds the basic message endpoints to a role's protected router
+func RegisterRoutes(protected *mux.Router, deps server.Dependencies) {
+	controller := controllers.NewController(deps)
+	protected.HandleFunc("/connections/{connection_id}/messages", controller.SendMessage).Methods("POST")
+	protected.HandleFunc("/connections/{connection_id}/messages", controller.ListMessages).Methods("GET")
+}
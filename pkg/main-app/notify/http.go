@@ -0,0 +1,85 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// HTTPNotifier posts messages as JSON to an email service such as the
+// original lambda, which expects {"email": ..., "message": {"subject", "body"}}.
+// The HTML goes in body, the plain text alternative in text and attachments
+// are sent base64 encoded.
+type HTTPNotifier struct {
+	url  string
+	http *http.Client
+}
+
+type httpPayload struct {
+	Email       string           `json:"email"`
+	Message     httpMessage      `json:"message"`
+	Attachments []httpAttachment `json:"attachments,omitempty"`
+}
+
+type httpAttachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	ContentID   string `json:"content_id,omitempty"`
+	Content     []byte `json:"content"`
+}
+
+type httpMessage struct {
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+	Text    string `json:"text,omitempty"`
+}
+
+// NewHTTPNotifier posts to url. A nil client gets a 30 second timeout.
+func NewHTTPNotifier(url string, client *http.Client) *HTTPNotifier {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &HTTPNotifier{url: url, http: client}
+}
+
+func (n *HTTPNotifier) Send(ctx context.Context, msg Message) error {
+	body := httpPayload{
+		Email:   msg.To,
+		Message: httpMessage{Subject: msg.Subject, Body: msg.HTML, Text: msg.Text},
+	}
+	for _, a := range msg.Attachments {
+		body.Attachments = append(body.Attachments, httpAttachment{
+			Filename:    a.Filename,
+			ContentType: a.ContentType,
+			ContentID:   a.ContentID,
+			Content:     a.Data,
+		})
+	}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("notify: post to email service: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("notify: email service returned %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return nil
+}
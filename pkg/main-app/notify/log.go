@@ -0,0 +1,50 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// LogNotifier records messages instead of delivering them, for development
+// and tests. Messages are appended to a file, or written to the log when no
+// path is configured.
+type LogNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewLogNotifier(path string) *LogNotifier {
+	return &LogNotifier{path: path}
+}
+
+// Send records the plain text body, which is the readable one, falling back
+// to the HTML
+func (n *LogNotifier) Send(ctx context.Context, msg Message) error {
+	body := msg.Text
+	if body == "" {
+		body = msg.HTML
+	}
+	for _, a := range msg.Attachments {
+		body += fmt.Sprintf("\n[attachment %s, %s, %d bytes]", a.Filename, a.ContentType, len(a.Data))
+	}
+	if n.path == "" {
+		log.Printf("Notification to %s : %s\n%s", msg.To, msg.Subject, body)
+		return nil
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("notify: open %s: %w", n.path, err)
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().UTC().Format(time.RFC3339), msg.To, msg.Subject, body)
+	return err
+}
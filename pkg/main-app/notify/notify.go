@@ -0,0 +1,51 @@
+package notify
+
+import (
+	"context"
+	"digiauth/pkg/main-app/config"
+	"fmt"
+)
+
+// Drivers accepted in the notifier configuration
+const (
+	DriverHTTP = "http"
+	DriverSMTP = "smtp"
+	DriverLog  = "log"
+)
+
+// Message is an email to a single recipient with plain text and HTML bodies
+type Message struct {
+	To          string
+	Subject     string
+	Text        string
+	HTML        string
+	Attachments []Attachment
+}
+
+// Attachment is a file sent along with a message. Attachments with a
+// ContentID are shown inline, the HTML body refers to them as cid:<ContentID>.
+type Attachment struct {
+	Filename    string
+	ContentType string
+	ContentID   string
+	Data        []byte
+}
+
+// Notifier delivers messages to users, such as connection invitations
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the notifier selected by cfg.Driver
+func New(cfg config.Notifier) (Notifier, error) {
+	switch cfg.Driver {
+	case DriverHTTP:
+		return NewHTTPNotifier(cfg.HTTP.URL, nil), nil
+	case DriverSMTP:
+		return NewSMTPNotifier(cfg.SMTP), nil
+	case DriverLog:
+		return NewLogNotifier(cfg.Log.Path), nil
+	default:
+		return nil, fmt.Errorf("notify: unknown driver %q", cfg.Driver)
+	}
+}
@@ -0,0 +1,159 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"digiauth/pkg/main-app/config"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"time"
+)
+
+// SMTPNotifier sends messages through a mail server, authenticating with
+// PLAIN when a username is configured
+type SMTPNotifier struct {
+	cfg config.SMTP
+}
+
+func NewSMTPNotifier(cfg config.SMTP) *SMTPNotifier {
+	return &SMTPNotifier{cfg: cfg}
+}
+
+func (n *SMTPNotifier) Send(ctx context.Context, msg Message) error {
+	from, err := mail.ParseAddress(n.cfg.From)
+	if err != nil {
+		return fmt.Errorf("notify: invalid from address: %w", err)
+	}
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("notify: invalid recipient %q: %w", msg.To, err)
+	}
+
+	body, err := compose(from, to, msg)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if n.cfg.Username != "" {
+		auth = smtp.PlainAuth("", n.cfg.Username, n.cfg.Password, n.cfg.Host)
+	}
+
+	// net/smtp has no context support, run it aside so callers can give up
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(net.JoinHostPort(n.cfg.Host, n.cfg.Port), auth, from.Address, []string{to.Address}, body)
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("notify: send mail: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// compose renders the RFC 5322 message. The bodies form a
+// multipart/alternative with the plain text first; when there are
+// attachments it is wrapped in a multipart/related so inline images resolve.
+// Addresses and the subject are encoded so user input cannot inject headers.
+func compose(from, to *mail.Address, msg Message) ([]byte, error) {
+	var alternative bytes.Buffer
+	aw := multipart.NewWriter(&alternative)
+	if err := writePart(aw, "text/plain; charset=utf-8", msg.Text); err != nil {
+		return nil, err
+	}
+	if err := writePart(aw, "text/html; charset=utf-8", msg.HTML); err != nil {
+		return nil, err
+	}
+	if err := aw.Close(); err != nil {
+		return nil, err
+	}
+	alternativeType := mime.FormatMediaType("multipart/alternative", map[string]string{"boundary": aw.Boundary()})
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from.String())
+	fmt.Fprintf(&b, "To: %s\r\n", to.String())
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if len(msg.Attachments) == 0 {
+		fmt.Fprintf(&b, "Content-Type: %s\r\n\r\n", alternativeType)
+		b.Write(alternative.Bytes())
+		return b.Bytes(), nil
+	}
+
+	rw := multipart.NewWriter(&b)
+	fmt.Fprintf(&b, "Content-Type: %s\r\n\r\n", mime.FormatMediaType("multipart/related", map[string]string{"boundary": rw.Boundary(), "type": "multipart/alternative"}))
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", alternativeType)
+	part, err := rw.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := part.Write(alternative.Bytes()); err != nil {
+		return nil, err
+	}
+	for _, a := range msg.Attachments {
+		if err := writeAttachment(rw, a); err != nil {
+			return nil, err
+		}
+	}
+	if err := rw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func writePart(mw *multipart.Writer, contentType, body string) error {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func writeAttachment(mw *multipart.Writer, a Attachment) error {
+	disposition := "attachment"
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", a.ContentType)
+	header.Set("Content-Transfer-Encoding", "base64")
+	if a.ContentID != "" {
+		disposition = "inline"
+		header.Set("Content-ID", "<"+a.ContentID+">")
+	}
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	// Base64 bodies are wrapped at 76 characters per RFC 2045
+	encoded := base64.StdEncoding.EncodeToString(a.Data)
+	for len(encoded) > 76 {
+		if _, err := io.WriteString(part, encoded[:76]+"\r\n"); err != nil {
+			return err
+		}
+		encoded = encoded[76:]
+	}
+	_, err = io.WriteString(part, encoded+"\r\n")
+	return err
+}
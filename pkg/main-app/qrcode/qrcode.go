@@ -0,0 +1,59 @@
+package qrcode
+
+import (
+	"bytes"
+	"fmt"
+
+	qr "github.com/skip2/go-qrcode"
+)
+
+// Content types of the supported image formats
+const (
+	PNGContentType = "image/png"
+	SVGContentType = "image/svg+xml"
+)
+
+// DefaultSize is the width in pixels of PNG codes when none is requested
+const DefaultSize = 320
+
+// Medium recovery keeps invitation URLs, which can run past 1 KB, at a size
+// phone cameras still read reliably
+const recovery = qr.Medium
+
+// PNG encodes content as a square PNG size pixels wide
+func PNG(content string, size int) ([]byte, error) {
+	png, err := qr.Encode(content, recovery, size)
+	if err != nil {
+		return nil, fmt.Errorf("qrcode: %w", err)
+	}
+	return png, nil
+}
+
+// SVG encodes content as a scalable SVG drawing one path for all dark modules
+func SVG(content string) ([]byte, error) {
+	code, err := qr.New(content, recovery)
+	if err != nil {
+		return nil, fmt.Errorf("qrcode: %w", err)
+	}
+	bitmap := code.Bitmap()
+	size := len(bitmap)
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, size, size)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#ffffff"/><path fill="#000000" d="`, size, size)
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			// Merge runs of dark modules on a row into one rectangle
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&b, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+	b.WriteString(`"/></svg>`)
+	return b.Bytes(), nil
+}
@@ -0,0 +1,221 @@
+package revocations
+
+import (
+	"context"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/auth"
+	"digiauth/pkg/main-app/db"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"digiauth/pkg/main-app/revocations"
+	models "digiauth/pkg/main-app/revocations/models"
+	"digiauth/pkg/main-app/server"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Controller revokes the credentials the caller issued, lists the
+// revocations they made and publishes the pending ones
+type Controller struct {
+	role      string
+	agent     acapy.Agent
+	store     *db.Store
+	publisher *revocations.Publisher
+}
+
+func NewController(deps server.Dependencies) *Controller {
+	return &Controller{
+		role:      deps.Role,
+		agent:     deps.Agent,
+		store:     deps.Store,
+		publisher: revocations.NewPublisher(deps),
+	}
+}
+
+// Revoke revokes one of the caller's issued credentials with the agent and
+// records who revoked it, when and why. With publish the revocation goes to
+// the ledger straight away, otherwise it stays pending until the next batch
+// is published. With notify the holder is told over the connection the
+// credential was issued on.
+func (c *Controller) Revoke(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var requestData models.RevokeRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+		return
+	}
+	byEntry := requestData.RevRegID != "" || requestData.CredRevID != ""
+	if requestData.CredExID == "" && (requestData.RevRegID == "" || requestData.CredRevID == "") {
+		http.Error(w, "cred_ex_id, or rev_reg_id and cred_rev_id, are required", http.StatusBadRequest)
+		return
+	}
+	if requestData.CredExID != "" && byEntry {
+		http.Error(w, "Give either cred_ex_id or rev_reg_id and cred_rev_id, not both", http.StatusBadRequest)
+		return
+	}
+	requestData.Reason = strings.TrimSpace(requestData.Reason)
+	if len(requestData.Reason) > revocations.MaxReasonLength {
+		http.Error(w, "reason must be at most 1000 bytes", http.StatusBadRequest)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	var exchange sql.CredentialExchange
+	var err error
+	if byEntry {
+		exchange, err = c.store.GetCredentialExchangeByRevocationEntry(ctx, sql.GetCredentialExchangeByRevocationEntryParams{
+			RevRegID:  requestData.RevRegID,
+			CredRevID: requestData.CredRevID,
+		})
+	} else {
+		exchange, err = c.store.GetCredentialExchange(ctx, requestData.CredExID)
+	}
+	// Only credentials the caller issued can be revoked
+	if errors.Is(err, pgx.ErrNoRows) || (err == nil && (exchange.UserID != user.ID || exchange.Role != c.role)) {
+		http.Error(w, "Credential exchange not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("Error fetching credential exchange from db : ", err.Error())
+		http.Error(w, "Error fetching credential exchange from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	_, err = c.store.GetCredentialRevocationByCredExID(ctx, exchange.CredExID)
+	if err == nil {
+		http.Error(w, "Credential already revoked", http.StatusConflict)
+		return
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		log.Println("Error fetching revocation from db : ", err.Error())
+		http.Error(w, "Error fetching revocation from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	revokeRequest := acapy.RevokeRequest{
+		Publish: requestData.Publish,
+		Notify:  requestData.Notify,
+		Comment: requestData.Reason,
+	}
+	if byEntry {
+		revokeRequest.RevRegID = requestData.RevRegID
+		revokeRequest.CredRevID = requestData.CredRevID
+	} else {
+		revokeRequest.CredExID = exchange.CredExID
+	}
+	if requestData.Notify {
+		revokeRequest.NotifyVersion = revocations.NotifyVersion
+		revokeRequest.ConnectionID = exchange.ConnectionID
+		revokeRequest.ThreadID = exchange.ThreadID
+	}
+	if err := c.agent.Revoke(ctx, revokeRequest); err != nil {
+		log.Println("Failed to revoke credential: ", err)
+		http.Error(w, "Failed to revoke credential: "+err.Error(), acapy.StatusCode(err))
+		return
+	}
+
+	var publishedAt pgtype.Timestamptz
+	if requestData.Publish {
+		publishedAt = pgtype.Timestamptz{Time: time.Now(), Valid: true}
+	}
+	revocation, err := c.store.CreateCredentialRevocation(ctx, sql.CreateCredentialRevocationParams{
+		CredExID:       exchange.CredExID,
+		RevRegID:       exchange.RevRegID,
+		CredRevID:      exchange.CredRevID,
+		ConnectionID:   exchange.ConnectionID,
+		RevokedBy:      user.ID,
+		RevokedByEmail: user.Email,
+		Reason:         requestData.Reason,
+		Notified:       requestData.Notify,
+		PublishedAt:    publishedAt,
+	})
+	if err != nil {
+		// The agent has revoked the credential already, so only the record is missing
+		log.Println("Error inserting revocation to db : ", err.Error())
+		http.Error(w, "Credential revoked but the revocation could not be recorded : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{"revocation": toRevocation(revocation)})
+}
+
+// ListRevocations returns the revocations the caller made, newest first.
+// ?status=pending or ?status=published lists only those not yet or already
+// on the ledger.
+func (c *Controller) ListRevocations(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	status := r.URL.Query().Get("status")
+	if status != "" && status != revocations.StatusPending && status != revocations.StatusPublished {
+		http.Error(w, "status must be pending or published", http.StatusBadRequest)
+		return
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	rows, err := c.store.ListCredentialRevocations(ctx, sql.ListCredentialRevocationsParams{
+		RevokedBy: user.ID,
+		Status:    status,
+	})
+	if err != nil {
+		log.Println("Error fetching revocations from db : ", err.Error())
+		http.Error(w, "Error fetching revocations from db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	revocationList := []models.Revocation{}
+	for _, row := range rows {
+		revocationList = append(revocationList, toRevocation(row))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"revocations": revocationList})
+}
+
+// PublishRevocations publishes the caller's pending revocations to the
+// ledger now instead of waiting for the next scheduled batch
+func (c *Controller) PublishRevocations(w http.ResponseWriter, r *http.Request) {
+	user, _ := auth.UserFromContext(r.Context())
+	published, err := c.publisher.Publish(context.Background(), user.ID)
+	if err != nil {
+		log.Println("Failed to publish revocations: ", err)
+		http.Error(w, "Failed to publish revocations: "+err.Error(), acapy.StatusCode(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Pending revocations published",
+		"published": published,
+	})
+}
+
+func toRevocation(row sql.CredentialRevocation) models.Revocation {
+	revocation := models.Revocation{
+		ID:             row.ID,
+		CredExID:       row.CredExID,
+		RevRegID:       row.RevRegID,
+		CredRevID:      row.CredRevID,
+		ConnectionID:   row.ConnectionID,
+		RevokedByEmail: row.RevokedByEmail,
+		Reason:         row.Reason,
+		Notified:       row.Notified,
+		Status:         revocations.StatusPending,
+		RevokedAt:      row.RevokedAt.Time,
+	}
+	if row.PublishedAt.Valid {
+		revocation.Status = revocations.StatusPublished
+		revocation.PublishedAt = &row.PublishedAt.Time
+	}
+	return revocation
+}
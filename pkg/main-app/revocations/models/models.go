@@ -0,0 +1,28 @@
+package revocations
+
+import "time"
+
+// RevokeRequest names the credential by its cred_ex_id, or by its revocation
+// registry and index within it
+type RevokeRequest struct {
+	CredExID  string `json:"cred_ex_id"`
+	RevRegID  string `json:"rev_reg_id"`
+	CredRevID string `json:"cred_rev_id"`
+	Publish   bool   `json:"publish"`
+	Notify    bool   `json:"notify"`
+	Reason    string `json:"reason"`
+}
+
+type Revocation struct {
+	ID             int64      `json:"id"`
+	CredExID       string     `json:"cred_ex_id"`
+	RevRegID       string     `json:"rev_reg_id"`
+	CredRevID      string     `json:"cred_rev_id"`
+	ConnectionID   string     `json:"connection_id"`
+	RevokedByEmail string     `json:"revoked_by"`
+	Reason         string     `json:"reason"`
+	Notified       bool       `json:"notified"`
+	Status         string     `json:"status"`
+	RevokedAt      time.Time  `json:"revoked_at"`
+	PublishedAt    *time.Time `json:"published_at"`
+}
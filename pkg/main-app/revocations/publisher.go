@@ -0,0 +1,126 @@
+package revocations
+
+import (
+	"context"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/db"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"digiauth/pkg/main-app/server"
+	"errors"
+	"log"
+	"time"
+)
+
+// Publisher publishes pending revocations to the ledger in batches, one
+// registry update for all the revocations of a registry instead of one per
+// credential
+type Publisher struct {
+	agent    acapy.Agent
+	store    *db.Store
+	interval time.Duration
+}
+
+func NewPublisher(deps server.Dependencies) *Publisher {
+	return &Publisher{
+		agent:    deps.Agent,
+		store:    deps.Store,
+		interval: time.Duration(deps.Config.Revocations.PublishInterval),
+	}
+}
+
+// Run publishes on every interval until ctx is done. It returns straight
+// away when the interval is zero.
+func (p *Publisher) Run(ctx context.Context) {
+	if p.interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if _, err := p.Publish(ctx, 0); err != nil && ctx.Err() == nil {
+			log.Printf("Error publishing pending revocations : %v", err)
+		}
+	}
+}
+
+// Publish publishes the pending revocations made by the user revokedBy, or
+// by anyone when it is zero, and returns how many were published. Entries
+// the agent no longer has pending are left for the next run to retry.
+func (p *Publisher) Publish(ctx context.Context, revokedBy int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+	defer cancel()
+
+	pending, err := p.store.ListPendingRevocations(ctx, revokedBy)
+	if err != nil {
+		return 0, err
+	}
+
+	rrid2crid := map[string][]string{}
+	for i, row := range pending {
+		// Revoked before the issue webhook told us the registry entry
+		if row.RevRegID == "" || row.CredRevID == "" {
+			if pending[i], err = p.resolve(ctx, row); err != nil {
+				log.Printf("Error finding the registry entry of credential exchange %s : %v", row.CredExID, err)
+				continue
+			}
+			row = pending[i]
+		}
+		rrid2crid[row.RevRegID] = append(rrid2crid[row.RevRegID], row.CredRevID)
+	}
+	if len(rrid2crid) == 0 {
+		return 0, nil
+	}
+
+	published, err := p.agent.PublishRevocations(ctx, rrid2crid)
+	if err != nil {
+		return 0, err
+	}
+	done := map[string]bool{}
+	for revRegID, credRevIDs := range published {
+		for _, credRevID := range credRevIDs {
+			done[revRegID+"|"+credRevID] = true
+		}
+	}
+	var ids []int64
+	for _, row := range pending {
+		if done[row.RevRegID+"|"+row.CredRevID] {
+			ids = append(ids, row.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	n, err := p.store.MarkRevocationsPublished(ctx, ids)
+	if err != nil {
+		return 0, err
+	}
+	log.Printf("Published %d pending revocations", n)
+	return n, nil
+}
+
+// resolve asks the agent for the registry entry of a revocation and stores it
+func (p *Publisher) resolve(ctx context.Context, row sql.CredentialRevocation) (sql.CredentialRevocation, error) {
+	record, err := p.agent.CredentialRevocationRecord(ctx, row.CredExID)
+	if err != nil {
+		return row, err
+	}
+	if record.RevRegID == "" || record.CredRevID == "" {
+		return row, errors.New("credential is not revocable")
+	}
+	err = p.store.SetRevocationEntry(ctx, sql.SetRevocationEntryParams{
+		RevRegID:  record.RevRegID,
+		CredRevID: record.CredRevID,
+		ID:        row.ID,
+	})
+	if err != nil {
+		return row, err
+	}
+	row.RevRegID, row.CredRevID = record.RevRegID, record.CredRevID
+	return row, nil
+}
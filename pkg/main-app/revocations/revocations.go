@@ -0,0 +1,15 @@
+package revocations
+
+// MaxReasonLength bounds the reason recorded with a revocation, in bytes
+const MaxReasonLength = 1000
+
+// NotifyVersion is the revocation notification protocol holders are told
+// with
+const NotifyVersion = "v1_0"
+
+// Statuses of recorded revocations. Pending ones are revoked in the agent's
+// wallet but not yet on the ledger, so verifiers still accept the credential.
+const (
+	StatusPending   = "pending"
+	StatusPublished = "published"
+)
@@ -0,0 +1,16 @@
+package revocations
+
+import (
+	controllers "digiauth/pkg/main-app/revocations/controllers"
+	"digiauth/pkg/main-app/server"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes adds credential revocation to the issuer's protected router
+func RegisterRoutes(protected *mux.Router, deps server.Dependencies) {
+	controller := controllers.NewController(deps)
+	protected.HandleFunc("/revocation/revoke", controller.Revoke).Methods("POST")
+	protected.HandleFunc("/revocations", controller.ListRevocations).Methods("GET")
+	protected.HandleFunc("/revocations/publish", controller.PublishRevocations).Methods("POST")
+}
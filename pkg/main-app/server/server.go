@@ -0,0 +1,26 @@
+package server
+
+import (
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/auth"
+	"digiauth/pkg/main-app/config"
+	"digiauth/pkg/main-app/db"
+	"digiauth/pkg/main-app/emails"
+	"digiauth/pkg/main-app/events"
+	"digiauth/pkg/main-app/exchanges"
+	"digiauth/pkg/main-app/notify"
+)
+
+// Dependencies are the services a role server is built from. Role, Agent and
+// Events belong to the server's own role, the rest is shared.
+type Dependencies struct {
+	Role     string
+	Config   *config.Config
+	Agent    acapy.Agent
+	Store    *db.Store
+	Tokens   *auth.TokenManager
+	Events   *events.Broker
+	Notifier notify.Notifier
+	Emails   *emails.Renderer
+	Sealer   *exchanges.Sealer
+}
@@ -3,103 +3,140 @@ package receiver
 import (
 	"bytes"
 	"context"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/auth"
+	"digiauth/pkg/main-app/config"
+	connstate "digiauth/pkg/main-app/connections"
 	"digiauth/pkg/main-app/db"
 	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"digiauth/pkg/main-app/emails"
+	"digiauth/pkg/main-app/invitations"
+	"digiauth/pkg/main-app/notify"
+	"digiauth/pkg/main-app/server"
 	models "digiauth/pkg/main-app/user/models"
 	"encoding/json"
-	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-func GetConnections(w http.ResponseWriter, r *http.Request) {
+// Controller serves the HTTP handlers, talking to the agent configured for this
+// role and to the shared store
+type Controller struct {
+	cfg      *config.Config
+	agent    acapy.Agent
+	store    *db.Store
+	notifier notify.Notifier
+	emails   *emails.Renderer
+	tokens   *auth.TokenManager
+}
+
+func NewController(deps server.Dependencies) *Controller {
+	return &Controller{
+		cfg:      deps.Config,
+		agent:    deps.Agent,
+		store:    deps.Store,
+		notifier: deps.Notifier,
+		emails:   deps.Emails,
+		tokens:   deps.Tokens,
+	}
+}
+
+func (c *Controller) GetConnections(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
-	var req models.GetConnectionsRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	user, _ := auth.UserFromContext(r.Context())
+
+	// The query string filters, sorts and pages the connections of this
+	// server's agent
+	params, limit, err := connstate.ListParams(r, user.ID, config.RoleHolder)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	queries := sql.New(db.DB)
-	connections, conerr := queries.GetConnectionsByUserID(ctx, req.Id)
+	connections, conerr := c.store.ListConnections(ctx, params)
 	if conerr != nil {
 		log.Println("Error getting connection to db : ", conerr.Error())
 		http.Error(w, "Error getting connection to db : "+conerr.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	connections, nextCursor := connstate.Page(connections, limit)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"connections": connections})
+	json.NewEncoder(w).Encode(map[string]interface{}{"connections": connections, "next_cursor": nextCursor})
 }
 
-func GetCredentials(w http.ResponseWriter, r *http.Request) {
+// GetCredentials returns the credentials in the holder wallet that were
+// issued to the caller. The wallet is shared by all holders, credentials
+// are attributed through the exchanges they were stored from.
+func (c *Controller) GetCredentials(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
 
-	resp, err := http.Get("http://localhost:6041/credentials")
+	user, _ := auth.UserFromContext(r.Context())
+	credIDs, err := c.store.ListHeldCredentialIDs(ctx, sql.ListHeldCredentialIDsParams{
+		UserID: user.ID,
+		Role:   config.RoleHolder,
+	})
 	if err != nil {
-		http.Error(w, "Failed to contact external service", http.StatusInternalServerError)
+		log.Println("Error fetching held credentials from db : ", err.Error())
+		http.Error(w, "Error fetching held credentials from db : "+err.Error(), http.StatusInternalServerError)
 		return
 	}
-	defer resp.Body.Close()
+	held := map[string]bool{}
+	for _, credID := range credIDs {
+		held[credID] = true
+	}
 
-	// Read the response from the external service
-	body, err := io.ReadAll(resp.Body)
+	credentials, err := c.agent.ListCredentials(ctx)
 	if err != nil {
-		http.Error(w, "Failed to read response", http.StatusInternalServerError)
+		log.Println("Failed to fetch credentials: ", err)
+		http.Error(w, "Failed to fetch credentials: "+err.Error(), acapy.StatusCode(err))
 		return
 	}
 
-	// Return the response from the external service to the original caller
+	results := []acapy.Credential{}
+	for _, credential := range credentials {
+		if held[credential.Referent] {
+			results = append(results, credential)
+		}
+	}
+
+	// Return the caller's credentials held by the agent
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(body)
+	json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
 }
 
-func ReceiveInvitation(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) ReceiveInvitation(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 	var requestData models.ReceiveInvitationRequest
 	//Decode the request body into req struct
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || len(requestData.Invitation) == 0 {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
-	// Convert the req struct to JSON for the external request
-	requestBody, err := json.Marshal(requestData.Invitation)
-	if err != nil {
-		http.Error(w, "Failed to marshal request", http.StatusInternalServerError)
-		return
-	}
-
-	resp, err := http.Post("http://localhost:6041/connections/receive-invitation", "application/json", bytes.NewBuffer(requestBody))
-	if err != nil {
-		http.Error(w, "Failed to contact external service", http.StatusInternalServerError)
-		return
-	}
-	defer resp.Body.Close()
+	user, _ := auth.UserFromContext(r.Context())
 
-	// Read the response from the external service
-	body, err := io.ReadAll(resp.Body)
+	// Invitations created by these servers are checked and claimed before
+	// the agent receives them
+	connectionID, responseData, err := invitations.Receive(ctx, c.store, c.agent, config.RoleHolder, requestData.Invitation)
 	if err != nil {
-		http.Error(w, "Failed to read response", http.StatusInternalServerError)
-		return
-	}
-
-	var responseData models.ResponseReceiveInvitation
-	err = json.Unmarshal(body, &responseData)
-	if err != nil {
-		http.Error(w, "Failed to parse response", http.StatusInternalServerError)
+		invitations.WriteError(w, err)
 		return
 	}
 	log.Println("response data for receiving: ", responseData)
-	queries := sql.New(db.DB)
-	insertDBErr := queries.CreateConnection(ctx, sql.CreateConnectionParams{
-		ConnectionID: responseData.ConnectionID,
-		ID:           requestData.UserID,
-		MyMailID:     requestData.MyMailId,
+	insertDBErr := c.store.CreateConnection(ctx, sql.CreateConnectionParams{
+		ConnectionID: connectionID,
+		ID:           user.ID,
+		MyMailID:     user.Email,
 		TheirMailID:  requestData.TheirMailId,
+		Role:         config.RoleHolder,
 	})
 	if insertDBErr != nil {
 		log.Println("Error inserting connection to db : ", insertDBErr.Error())
@@ -108,13 +145,13 @@ func ReceiveInvitation(w http.ResponseWriter, r *http.Request) {
 
 	}
 
-	// Return the response from the external service to the original caller
+	// Return the response from the agent to the original caller
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(body)
+	json.NewEncoder(w).Encode(responseData)
 }
 
 // This is the function to create invitation for connection
-func CreateInvitation(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) CreateInvitation(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
@@ -130,42 +167,26 @@ func CreateInvitation(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("request data: ", requestData)
 
-	resp, err := http.Post("http://localhost:8041/connections/create-invitation", "application/json", bytes.NewBuffer([]byte{}))
-	if err != nil {
-		http.Error(w, "Failed to contact external service", http.StatusInternalServerError)
-		return
-	}
-	defer resp.Body.Close()
+	user, _ := auth.UserFromContext(r.Context())
 
-	// Read the response from the external service
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		http.Error(w, "Failed to read response", http.StatusInternalServerError)
+	if len(requestData.Attachments) > 0 && c.cfg.Agents.InvitationProtocol != acapy.ProtocolOutOfBand {
+		http.Error(w, "Attachments need out-of-band invitations", http.StatusBadRequest)
 		return
 	}
 
-	// Parse the JSON response
-	var responseData models.InvitationResponse
-
-	err = json.Unmarshal(body, &responseData)
+	responseData, err := acapy.NewInvitation(ctx, c.agent, c.cfg.Agents.InvitationProtocol, requestData.Attachments)
 	if err != nil {
-		http.Error(w, "Failed to parse response", http.StatusInternalServerError)
+		log.Println("Failed to create invitation: ", err)
+		http.Error(w, "Failed to create invitation: "+err.Error(), acapy.StatusCode(err))
 		return
 	}
 
-	// Print the JSON string
-	invitationString, marshalErr := json.Marshal(responseData.Invitation)
-	if marshalErr != nil {
-		fmt.Println("Error:", marshalErr)
-		return
-	}
-
-	queries := sql.New(db.DB)
-	insertDBErr := queries.CreateConnection(ctx, sql.CreateConnectionParams{
+	insertDBErr := c.store.CreateConnection(ctx, sql.CreateConnectionParams{
 		ConnectionID: responseData.ConnectionID,
-		ID:           requestData.Id,
-		MyMailID:     requestData.MyMailId,
+		ID:           user.ID,
+		MyMailID:     user.Email,
 		TheirMailID:  requestData.TheirMailId,
+		Role:         config.RoleHolder,
 	})
 
 	if insertDBErr != nil {
@@ -174,32 +195,56 @@ func CreateInvitation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	emailBody := models.SendEmail{
-		Email: requestData.TheirMailId,
-		Message: models.Message{
-			Subject: "Invitation to connect",
-			Body:    "{<h5 style=\"margin:0;padding:0\">\"invitation\":" + string(invitationString) + ",<br/>\"their_mail_id\":\"" + requestData.MyMailId + "\"</h5>}",
-		},
+	// Track the invitation so it can expire, be cancelled and be shown again
+	// as a QR code
+	expiresAt := time.Now().Add(time.Duration(c.cfg.Invitations.TTL))
+	err = c.store.CreateInvitation(ctx, sql.CreateInvitationParams{
+		ConnectionID:   responseData.ConnectionID,
+		Role:           config.RoleHolder,
+		Invitation:     responseData.Invitation,
+		InvitationID:   acapy.InvitationID(responseData.Invitation),
+		UserID:         user.ID,
+		RecipientEmail: requestData.TheirMailId,
+		ExpiresAt:      pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		log.Println("Error inserting invitation to db : ", err.Error())
+		http.Error(w, "Error inserting invitation to db : "+err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	emailPayload, err := json.Marshal(emailBody)
+	// The email's link accepts the invitation for a recipient who is logged in
+	token, err := c.tokens.IssueInvitation(responseData.ConnectionID, requestData.TheirMailId, expiresAt)
 	if err != nil {
-		fmt.Println("Error in Marshalling emailbody:", err)
+		log.Println("Error signing invitation link : ", err.Error())
+		http.Error(w, "Failed to prepare invitation email", http.StatusInternalServerError)
 		return
 	}
 
-	_, mailErr := http.Post("https://q648rhgza1.execute-api.ap-south-1.amazonaws.com/prod", "application/json", bytes.NewBuffer(emailPayload))
-	if mailErr != nil {
-		log.Println("Failed to send email in create invitation")
-		http.Error(w, "Failed to send email", http.StatusInternalServerError)
+	msg, err := c.emails.Invitation(requestData.TheirMailId, config.RoleHolder, user.Email, responseData.Invitation, expiresAt, token)
+	if err != nil {
+		log.Println("Error rendering invitation email : ", err.Error())
+		http.Error(w, "Failed to prepare invitation email", http.StatusInternalServerError)
+		return
 	}
 
+	err = c.notifier.Send(ctx, msg)
+	if err != nil {
+		log.Println("Failed to send email in create invitation: ", err)
+		http.Error(w, "Failed to send email", http.StatusBadGateway)
+		return
+	}
+
+	// The connection ID lets the frontend show the invitation's QR code
 	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"message": "Invitation Sent Successfully"}`))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":       "Invitation Sent Successfully",
+		"connection_id": responseData.ConnectionID,
+	})
 }
 
 // This is the function for registering DID with Ledger
-func RegisterDID(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) RegisterDID(w http.ResponseWriter, r *http.Request) {
 	var req models.RegisterDIDRequest
 
 	// Decode the request body into the req struct
@@ -216,7 +261,7 @@ func RegisterDID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send the request to the external endpoint
-	resp, err := http.Post("http://test.bcovrin.vonx.io/register", "application/json", bytes.NewBuffer(requestBody))
+	resp, err := http.Post(c.cfg.LedgerURL, "application/json", bytes.NewBuffer(requestBody))
 	if err != nil {
 		http.Error(w, "Failed to contact external service", http.StatusInternalServerError)
 		return
@@ -235,95 +280,78 @@ func RegisterDID(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
-func GetRecords(ConnectionId string) (models.ProofRecord, error) {
-	var allRecords models.ProofRecords
-	var singleRecord models.ProofRecord
-	var responseBody models.ProofRecord
-	resp, err := http.Get("http://localhost:6041/present-proof-2.0/records")
-	if err != nil {
-		log.Println("Failed to contact external service")
-		return models.ProofRecord{}, err
-	}
-	defer resp.Body.Close()
-
-	// Read the response from the external service
-	body, err := io.ReadAll(resp.Body)
+// GetRecords returns the presentation exchange of the connection that is waiting
+// for the holder to answer a proof request
+func (c *Controller) GetRecords(ctx context.Context, ConnectionId string) (acapy.PresentationExchange, error) {
+	records, err := c.agent.ListPresentationRecords(ctx)
 	if err != nil {
-		log.Println("Failed to read response")
-		return models.ProofRecord{}, err
+		log.Println("Failed to fetch presentation records: ", err)
+		return acapy.PresentationExchange{}, err
 	}
 
-	err = json.Unmarshal(body, &allRecords)
-	if err != nil {
-		log.Println("Failed to unmarshall body into allRecords")
-		return models.ProofRecord{}, err
-	}
-
-	for i := 0; i < len(allRecords.Results); i++ {
-		err = json.Unmarshal(body, &singleRecord)
-		if err != nil {
-			log.Println("Failed to unmarshall body into singleRecord")
-			return models.ProofRecord{}, err
-		}
-		if singleRecord.ConnectionId == ConnectionId {
-			if singleRecord.State == "request-received" {
-				responseBody = singleRecord
-			}
+	var responseBody acapy.PresentationExchange
+	for _, record := range records {
+		if record.ConnectionID == ConnectionId && record.State == "request-received" {
+			responseBody = record
 		}
 	}
 
 	return responseBody, nil
 }
 
-func SendPresentation(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) SendPresentation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
 	var req models.SendPresentationRequest
-	var sendPresentationResponse models.SendPresentationResponse
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
-	record, err := GetRecords(req.ConnectionID)
-	if err != nil {
-		log.Println("GetRecords not working properly")
-		http.Error(w, "Failed to get records", http.StatusInternalServerError)
+	user, _ := auth.UserFromContext(r.Context())
+	if err := auth.AuthorizeConnection(ctx, c.store, user, config.RoleHolder, req.ConnectionID); err != nil {
+		auth.WriteAuthorizationError(w, err)
 		return
 	}
 
-	url := fmt.Sprintf("http://localhost:6041/present-proof-2.0/records/%s/send-presentation", record.Pres_Ex_Id)
-
-	requestBody, err := json.Marshal(req)
+	record, err := c.GetRecords(ctx, req.ConnectionID)
 	if err != nil {
-		http.Error(w, "Failed to marshal request", http.StatusInternalServerError)
+		log.Println("GetRecords not working properly")
+		http.Error(w, "Failed to get records", acapy.StatusCode(err))
 		return
 	}
-
-	response, err := http.Post(url, "application/json", bytes.NewBuffer(requestBody))
-	if err != nil {
-		http.Error(w, "Failed to send presentation", http.StatusInternalServerError)
+	if record.PresExID == "" {
+		http.Error(w, "No pending proof request for this connection", http.StatusNotFound)
 		return
 	}
-	defer response.Body.Close()
 
-	body, err := io.ReadAll(response.Body)
+	presentation, err := c.agent.SendPresentation(ctx, record.PresExID, acapy.PresentationSpec{
+		Indy:       req.Indy,
+		AutoRemove: req.AutoRemove,
+		Trace:      req.Trace,
+	})
 	if err != nil {
-		http.Error(w, "Failed to read response from send presentation", http.StatusInternalServerError)
+		log.Println("Failed to send presentation: ", err)
+		http.Error(w, "Failed to send presentation", acapy.StatusCode(err))
 		return
 	}
 
-	if response.StatusCode != http.StatusOK {
-		log.Printf("Non-200 Response: %s, Body: %s", response.Status, string(body))
-		http.Error(w, "Failed to send presentation", http.StatusInternalServerError)
-		return
-	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"response": models.SendPresentationResponse{State: presentation.State}})
+}
 
-	err = json.Unmarshal(body, &sendPresentationResponse)
-	if err != nil {
-		http.Error(w, "Failed to unmarshal body from send presentation", http.StatusInternalServerError)
-		return
-	}
+// Health reports whether the server can reach its database
+func (c *Controller) Health(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"response": sendPresentationResponse})
+	if err := c.store.Ping(ctx); err != nil {
+		log.Println("Health check failed: ", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status": "unavailable"}`))
+		return
+	}
+	w.Write([]byte(`{"status": "ok"}`))
 }
@@ -3,82 +3,101 @@ package verifier
 import (
 	"bytes"
 	"context"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/auth"
+	"digiauth/pkg/main-app/config"
+	connstate "digiauth/pkg/main-app/connections"
 	"digiauth/pkg/main-app/db"
 	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"digiauth/pkg/main-app/emails"
+	"digiauth/pkg/main-app/invitations"
+	"digiauth/pkg/main-app/notify"
+	"digiauth/pkg/main-app/server"
 	models "digiauth/pkg/main-app/verifier/models"
 	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
 )
 
-func GetConnections(w http.ResponseWriter, r *http.Request) {
+// Controller serves the HTTP handlers, talking to the agent configured for this
+// role and to the shared store
+type Controller struct {
+	cfg      *config.Config
+	agent    acapy.Agent
+	store    *db.Store
+	notifier notify.Notifier
+	emails   *emails.Renderer
+	tokens   *auth.TokenManager
+}
+
+func NewController(deps server.Dependencies) *Controller {
+	return &Controller{
+		cfg:      deps.Config,
+		agent:    deps.Agent,
+		store:    deps.Store,
+		notifier: deps.Notifier,
+		emails:   deps.Emails,
+		tokens:   deps.Tokens,
+	}
+}
+
+func (c *Controller) GetConnections(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
-	var req models.GetConnectionsRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
+	user, _ := auth.UserFromContext(r.Context())
+
+	// The query string filters, sorts and pages the connections of this
+	// server's agent
+	params, limit, err := connstate.ListParams(r, user.ID, config.RoleVerifier)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	queries := sql.New(db.DB)
-	connections, conerr := queries.GetConnectionsByUserID(ctx, req.Id)
+	connections, conerr := c.store.ListConnections(ctx, params)
 	if conerr != nil {
 		log.Println("Error getting connection to db : ", conerr.Error())
 		http.Error(w, "Error getting connection to db : "+conerr.Error(), http.StatusInternalServerError)
 		return
 	}
 
+	connections, nextCursor := connstate.Page(connections, limit)
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{"connections": connections})
+	json.NewEncoder(w).Encode(map[string]interface{}{"connections": connections, "next_cursor": nextCursor})
 }
 
-func ReceiveInvitation(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) ReceiveInvitation(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 	var requestData models.ReceiveInvitationRequest
 	//Decode the request body into req struct
-	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil || len(requestData.Invitation) == 0 {
 		http.Error(w, "Invalid request payload", http.StatusBadRequest)
 		return
 	}
 
-	// Convert the req struct to JSON for the external request
-	requestBody, err := json.Marshal(requestData.Invitation)
-	if err != nil {
-		http.Error(w, "Failed to marshal request", http.StatusInternalServerError)
-		return
-	}
+	user, _ := auth.UserFromContext(r.Context())
 
-	resp, err := http.Post("http://localhost:6041/connections/receive-invitation", "application/json", bytes.NewBuffer(requestBody))
+	// Invitations created by these servers are checked and claimed before
+	// the agent receives them
+	connectionID, responseData, err := invitations.Receive(ctx, c.store, c.agent, config.RoleVerifier, requestData.Invitation)
 	if err != nil {
-		http.Error(w, "Failed to contact external service", http.StatusInternalServerError)
-		return
-	}
-	defer resp.Body.Close()
-
-	// Read the response from the external service
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		http.Error(w, "Failed to read response", http.StatusInternalServerError)
-		return
-	}
-
-	var responseData models.ResponseReceiveInvitation
-	err = json.Unmarshal(body, &responseData)
-	if err != nil {
-		http.Error(w, "Failed to parse response", http.StatusInternalServerError)
+		invitations.WriteError(w, err)
 		return
 	}
 	log.Println("response data for receiving: ", responseData)
-	queries := sql.New(db.DB)
-	insertDBErr := queries.CreateConnection(ctx, sql.CreateConnectionParams{
-		ConnectionID: responseData.ConnectionID,
-		ID:           requestData.UserID,
-		MyMailID:     requestData.MyMailId,
+	insertDBErr := c.store.CreateConnection(ctx, sql.CreateConnectionParams{
+		ConnectionID: connectionID,
+		ID:           user.ID,
+		MyMailID:     user.Email,
 		TheirMailID:  requestData.TheirMailId,
+		Role:         config.RoleVerifier,
 	})
 	if insertDBErr != nil {
 		log.Println("Error inserting connection to db : ", insertDBErr.Error())
@@ -87,13 +106,13 @@ func ReceiveInvitation(w http.ResponseWriter, r *http.Request) {
 
 	}
 
-	// Return the response from the external service to the original caller
+	// Return the response from the agent to the original caller
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(body)
+	json.NewEncoder(w).Encode(responseData)
 }
 
 // This is the function to create invitation for connection
-func CreateInvitation(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) CreateInvitation(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
@@ -109,42 +128,26 @@ func CreateInvitation(w http.ResponseWriter, r *http.Request) {
 
 	log.Println("request data: ", requestData)
 
-	resp, err := http.Post("http://localhost:8041/connections/create-invitation", "application/json", bytes.NewBuffer([]byte{}))
-	if err != nil {
-		http.Error(w, "Failed to contact external service", http.StatusInternalServerError)
-		return
-	}
-	defer resp.Body.Close()
+	user, _ := auth.UserFromContext(r.Context())
 
-	// Read the response from the external service
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		http.Error(w, "Failed to read response", http.StatusInternalServerError)
+	if len(requestData.Attachments) > 0 && c.cfg.Agents.InvitationProtocol != acapy.ProtocolOutOfBand {
+		http.Error(w, "Attachments need out-of-band invitations", http.StatusBadRequest)
 		return
 	}
 
-	// Parse the JSON response
-	var responseData models.InvitationResponse
-
-	err = json.Unmarshal(body, &responseData)
+	responseData, err := acapy.NewInvitation(ctx, c.agent, c.cfg.Agents.InvitationProtocol, requestData.Attachments)
 	if err != nil {
-		http.Error(w, "Failed to parse response", http.StatusInternalServerError)
+		log.Println("Failed to create invitation: ", err)
+		http.Error(w, "Failed to create invitation: "+err.Error(), acapy.StatusCode(err))
 		return
 	}
 
-	// Print the JSON string
-	invitationString, marshalErr := json.Marshal(responseData.Invitation)
-	if marshalErr != nil {
-		fmt.Println("Error:", marshalErr)
-		return
-	}
-
-	queries := sql.New(db.DB)
-	insertDBErr := queries.CreateConnection(ctx, sql.CreateConnectionParams{
+	insertDBErr := c.store.CreateConnection(ctx, sql.CreateConnectionParams{
 		ConnectionID: responseData.ConnectionID,
-		ID:           requestData.Id,
-		MyMailID:     requestData.MyMailId,
+		ID:           user.ID,
+		MyMailID:     user.Email,
 		TheirMailID:  requestData.TheirMailId,
+		Role:         config.RoleVerifier,
 	})
 
 	if insertDBErr != nil {
@@ -153,32 +156,56 @@ func CreateInvitation(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	emailBody := models.SendEmail{
-		Email: requestData.TheirMailId,
-		Message: models.Message{
-			Subject: "Invitation to connect",
-			Body:    "{<h5 style=\"margin:0;padding:0\">\"invitation\":" + string(invitationString) + ",<br/>\"their_mail_id\":\"" + requestData.MyMailId + "\"</h5>}",
-		},
+	// Track the invitation so it can expire, be cancelled and be shown again
+	// as a QR code
+	expiresAt := time.Now().Add(time.Duration(c.cfg.Invitations.TTL))
+	err = c.store.CreateInvitation(ctx, sql.CreateInvitationParams{
+		ConnectionID:   responseData.ConnectionID,
+		Role:           config.RoleVerifier,
+		Invitation:     responseData.Invitation,
+		InvitationID:   acapy.InvitationID(responseData.Invitation),
+		UserID:         user.ID,
+		RecipientEmail: requestData.TheirMailId,
+		ExpiresAt:      pgtype.Timestamptz{Time: expiresAt, Valid: true},
+	})
+	if err != nil {
+		log.Println("Error inserting invitation to db : ", err.Error())
+		http.Error(w, "Error inserting invitation to db : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// The email's link accepts the invitation for a recipient who is logged in
+	token, err := c.tokens.IssueInvitation(responseData.ConnectionID, requestData.TheirMailId, expiresAt)
+	if err != nil {
+		log.Println("Error signing invitation link : ", err.Error())
+		http.Error(w, "Failed to prepare invitation email", http.StatusInternalServerError)
+		return
 	}
 
-	emailPayload, err := json.Marshal(emailBody)
+	msg, err := c.emails.Invitation(requestData.TheirMailId, config.RoleVerifier, user.Email, responseData.Invitation, expiresAt, token)
 	if err != nil {
-		fmt.Println("Error in Marshalling emailbody:", err)
+		log.Println("Error rendering invitation email : ", err.Error())
+		http.Error(w, "Failed to prepare invitation email", http.StatusInternalServerError)
 		return
 	}
 
-	_, mailErr := http.Post("https://q648rhgza1.execute-api.ap-south-1.amazonaws.com/prod", "application/json", bytes.NewBuffer(emailPayload))
-	if mailErr != nil {
-		log.Println("Failed to send email in create invitation")
-		http.Error(w, "Failed to send email", http.StatusInternalServerError)
+	err = c.notifier.Send(ctx, msg)
+	if err != nil {
+		log.Println("Failed to send email in create invitation: ", err)
+		http.Error(w, "Failed to send email", http.StatusBadGateway)
+		return
 	}
 
+	// The connection ID lets the frontend show the invitation's QR code
 	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(`{"message": "Invitation Sent Successfully"}`))
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":       "Invitation Sent Successfully",
+		"connection_id": responseData.ConnectionID,
+	})
 }
 
 // This is the function for registering DID with Ledger
-func RegisterDID(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) RegisterDID(w http.ResponseWriter, r *http.Request) {
 	var req models.RegisterDIDRequest
 
 	// Decode the request body into the req struct
@@ -195,7 +222,7 @@ func RegisterDID(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Send the request to the external endpoint
-	resp, err := http.Post("http://test.bcovrin.vonx.io/register", "application/json", bytes.NewBuffer(requestBody))
+	resp, err := http.Post(c.cfg.LedgerURL, "application/json", bytes.NewBuffer(requestBody))
 	if err != nil {
 		http.Error(w, "Failed to contact external service", http.StatusInternalServerError)
 		return
@@ -214,7 +241,9 @@ func RegisterDID(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
-func SendProofRequest(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) SendProofRequest(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
 	var req models.SendProofRequestRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -222,41 +251,34 @@ func SendProofRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Convert the req struct to JSON for the external request
-	requestBody, err := json.Marshal(req)
-	if err != nil {
-		http.Error(w, "Failed to marshal request", http.StatusInternalServerError)
-		return
-	}
-
-	resp, err := http.Post("http://localhost:4041/present-proof-2.0/send-request", "application/json", bytes.NewBuffer(requestBody))
-	if err != nil {
-		http.Error(w, "Failed to contact external service", http.StatusInternalServerError)
+	user, _ := auth.UserFromContext(r.Context())
+	if err := auth.AuthorizeConnection(ctx, c.store, user, config.RoleVerifier, req.ConnectionID); err != nil {
+		auth.WriteAuthorizationError(w, err)
 		return
 	}
-	defer resp.Body.Close()
 
-	// Read the response from the external service
-	body, err := io.ReadAll(resp.Body)
+	presentationExchange, err := c.agent.SendProofRequest(ctx, acapy.ProofRequest{
+		ConnectionID:        req.ConnectionID,
+		PresentationRequest: req.PresentationRequest,
+		Trace:               req.Trace,
+	})
 	if err != nil {
-		http.Error(w, "Failed to read response", http.StatusInternalServerError)
+		log.Println("Failed to send proof request: ", err)
+		http.Error(w, "Failed to send proof request: "+err.Error(), acapy.StatusCode(err))
 		return
 	}
 
-	// Return the response from the external service to the original caller
+	// Return the response from the agent to the original caller
 	w.Header().Set("Content-Type", "application/json")
-	w.Write(body)
+	json.NewEncoder(w).Encode(presentationExchange)
 }
 
-func GetSchemasDB(w http.ResponseWriter, r *http.Request) {
+func (c *Controller) GetSchemasDB(w http.ResponseWriter, r *http.Request) {
 	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
 	defer cancel()
 
-	// Initialize the SQL queries struct
-	queries := sql.New(db.DB)
-
 	// Fetch the schema by ID from the database using GetSchemaById
-	res, err := queries.GetSchema(ctx)
+	res, err := c.store.GetSchema(ctx)
 	if err != nil {
 		log.Println("Error fetching schema from db:", err.Error())
 		http.Error(w, "Error fetching schema from db: "+err.Error(), http.StatusInternalServerError)
@@ -268,106 +290,173 @@ func GetSchemasDB(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]interface{}{"schema": res})
 }
 
-func GetRecords(ConnectionId string) (models.ProofRecord, error) {
-	var allRecords models.ProofRecords
-	var singleRecord models.ProofRecord
-	var responseBody models.ProofRecord
-	resp, err := http.Get("http://localhost:6041/present-proof-2.0/records")
-	if err != nil {
-		log.Println("Failed to contact external service")
-		return models.ProofRecord{}, err
+// VerifyPresentation reports whether a presentation answering one of the
+// caller's proof requests has been verified, from the events the verifier
+// agent's webhook stored. With pres_ex_id it checks that exchange, otherwise
+// the latest one on the caller's connection with their_mail_id, which the
+// exchange must also be on when both are given. The agent is only asked
+// about exchanges no event was stored for.
+func (c *Controller) VerifyPresentation(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	var request models.VerifyPresentationRequest
+	// Decode the request body into the req struct
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil || (request.PresExID == "" && request.TheirMailID == "") {
+		http.Error(w, "Invalid request payload, pres_ex_id or their_mail_id is required", http.StatusBadRequest)
+		return
 	}
-	defer resp.Body.Close()
 
-	// Read the response from the external service
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		log.Println("Failed to read response")
-		return models.ProofRecord{}, err
+	// Look up the verifier's own side of the connection, its records live in
+	// the verifier agent
+	user, _ := auth.UserFromContext(r.Context())
+	var connectionIDs []string
+	if request.TheirMailID != "" {
+		connections, getDBErr := c.store.FetchConnections(ctx, sql.FetchConnectionsParams{
+			MyMailID:    user.Email,
+			TheirMailID: request.TheirMailID,
+		})
+		if getDBErr != nil {
+			log.Println("Error fetching connections from db : ", getDBErr.Error())
+			http.Error(w, "Error fetching connections from db : "+getDBErr.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, connection := range connections {
+			err := auth.AuthorizeConnection(ctx, c.store, user, config.RoleVerifier, connection.ConnectionID)
+			if errors.Is(err, auth.ErrForbidden) {
+				continue
+			}
+			if err != nil {
+				auth.WriteAuthorizationError(w, err)
+				return
+			}
+			connectionIDs = append(connectionIDs, connection.ConnectionID)
+		}
+		if len(connectionIDs) == 0 {
+			writePresentationResult(w, false)
+			return
+		}
 	}
 
-	err = json.Unmarshal(body, &allRecords)
+	var exchange acapy.PresentationExchange
+	var found bool
+	var err error
+	if request.PresExID != "" {
+		exchange, found, err = c.presentationExchange(ctx, request.PresExID)
+	} else {
+		// Connections come newest first
+		exchange, found, err = c.latestPresentationExchange(ctx, connectionIDs[0])
+	}
 	if err != nil {
-		log.Println("Failed to unmarshall body into allRecords")
-		return models.ProofRecord{}, err
+		log.Println("Failed to fetch presentation state: ", err)
+		http.Error(w, "Failed to fetch presentation state: "+err.Error(), acapy.StatusCode(err))
+		return
+	}
+	if !found {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"message": "Presentation Not Found"}`))
+		return
 	}
 
-	for i := 0; i < len(allRecords.Results); i++ {
-		err = json.Unmarshal(body, &singleRecord)
-		if err != nil {
-			log.Println("Failed to unmarshall body into singleRecord")
-			return models.ProofRecord{}, err
-		}
-		if singleRecord.ConnectionId == ConnectionId {
-			if singleRecord.State == "request-received" {
-				responseBody = singleRecord
-			}
+	// Only the verifier who sent the proof request may check it
+	if err := auth.AuthorizeConnection(ctx, c.store, user, config.RoleVerifier, exchange.ConnectionID); err != nil {
+		auth.WriteAuthorizationError(w, err)
+		return
+	}
+	matches := request.TheirMailID == ""
+	for _, connectionID := range connectionIDs {
+		if connectionID == exchange.ConnectionID {
+			matches = true
 		}
 	}
 
-	return responseBody, nil
+	writePresentationResult(w, matches && exchange.State == "done" && exchange.Verified == "true")
 }
 
-// ! Search mymailId and theirmailid in connections
-// ! Make requests to /records(using their_mail_id)
-// ! check connection state to be "done" but only check for the matching connection id that were retrieved previously.
-// ! If state is done for any of the records then return "true" or "verified" for that connection
-func VerifyPresentation(w http.ResponseWriter, r *http.Request) {
-	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
-	defer cancel()
+// presentationExchange returns the latest state of the exchange presExID
+func (c *Controller) presentationExchange(ctx context.Context, presExID string) (acapy.PresentationExchange, bool, error) {
+	event, err := c.store.GetLatestPresentationExchangeEvent(ctx, sql.GetLatestPresentationExchangeEventParams{
+		Role:     config.RoleVerifier,
+		PresExID: presExID,
+	})
+	if err == nil {
+		return presentationFromEvent(event), true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return acapy.PresentationExchange{}, false, err
+	}
 
-	var request models.VerifyPresentationRequest
-	// Decode the request body into the req struct
-	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
-		http.Error(w, "Invalid request payload", http.StatusBadRequest)
-		return
+	record, err := c.agent.GetPresentationRecord(ctx, presExID)
+	if acapy.IsNotFound(err) {
+		return acapy.PresentationExchange{}, false, nil
 	}
+	return record, err == nil, err
+}
 
-	queries := sql.New(db.DB)
-	connections, getDBErr := queries.FetchConnections(ctx, sql.FetchConnectionsParams{
-		TheirMailID: request.MyMailId,
-		MyMailID:    request.TheirMailID,
+// latestPresentationExchange returns the state of the latest exchange on
+// connectionID
+func (c *Controller) latestPresentationExchange(ctx context.Context, connectionID string) (acapy.PresentationExchange, bool, error) {
+	event, err := c.store.GetLatestConnectionPresentationEvent(ctx, sql.GetLatestConnectionPresentationEventParams{
+		Role:         config.RoleVerifier,
+		ConnectionID: connectionID,
 	})
-
-	if getDBErr != nil {
-		log.Println("Error inserting connection to db : ", getDBErr.Error())
-		http.Error(w, "Error inserting connection to db : "+getDBErr.Error(), http.StatusInternalServerError)
-		return
+	if err == nil {
+		return presentationFromEvent(event), true, nil
 	}
-
-	resp, err := http.Get("http://localhost:6041/present-proof-2.0/records")
-	if err != nil {
-		log.Println("Failed to contact external service")
-		return
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return acapy.PresentationExchange{}, false, err
 	}
 
-	body, err := io.ReadAll(resp.Body)
+	records, err := c.agent.ListPresentationRecords(ctx)
 	if err != nil {
-		log.Println("Failed to read response")
-		return
+		return acapy.PresentationExchange{}, false, err
+	}
+	var latest acapy.PresentationExchange
+	found := false
+	for _, record := range records {
+		// The agent's timestamps share one format and sort as strings
+		if record.ConnectionID == connectionID && (!found || record.UpdatedAt > latest.UpdatedAt) {
+			latest = record
+			found = true
+		}
 	}
+	return latest, found, nil
+}
 
-	var responseData models.GetRecordsResponse
-	marshalErr := json.Unmarshal(body, &responseData)
-	if marshalErr != nil {
-		log.Println("Error marshalling records response", marshalErr)
-		http.Error(w, "Error marshalling records response: "+marshalErr.Error(), http.StatusInternalServerError)
-		return
+func presentationFromEvent(event sql.PresentationExchangeEvent) acapy.PresentationExchange {
+	return acapy.PresentationExchange{
+		PresExID:     event.PresExID,
+		ConnectionID: event.ConnectionID,
+		ThreadID:     event.ThreadID,
+		State:        event.State,
+		Verified:     event.Verified,
 	}
+}
 
+func writePresentationResult(w http.ResponseWriter, verified bool) {
 	w.Header().Set("Content-Type", "application/json")
-	for i := 0; i < len(responseData.Results); i++ {
-		if responseData.Results[i].ConnectionID == connections[0].ConnectionID {
-			if responseData.Results[i].State == "done" {
-				w.WriteHeader(http.StatusOK)
-				w.Write([]byte(`{"message": "Presentation Verified Successfully"}`))
-				return
-			}
-		}
+	if verified {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"message": "Presentation Verified Successfully"}`))
+		return
 	}
 
 	w.WriteHeader(http.StatusNotFound)
 	w.Write([]byte(`{"message": "Presentation Not Verified"}`))
-	defer resp.Body.Close()
+}
+
+// Health reports whether the server can reach its database
+func (c *Controller) Health(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
+	defer cancel()
 
+	w.Header().Set("Content-Type", "application/json")
+	if err := c.store.Ping(ctx); err != nil {
+		log.Println("Health check failed: ", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status": "unavailable"}`))
+		return
+	}
+	w.Write([]byte(`{"status": "ok"}`))
 }
@@ -1,20 +1,40 @@
 package verifier
 
 import (
+	account "digiauth/pkg/main-app/account/routes"
+	connections "digiauth/pkg/main-app/connections/routes"
+	invitations "digiauth/pkg/main-app/invitations/routes"
+	messages "digiauth/pkg/main-app/messages/routes"
+	"digiauth/pkg/main-app/server"
 	controllers "digiauth/pkg/main-app/verifier/controllers"
+	webhooks "digiauth/pkg/main-app/webhooks/routes"
+	"net/http"
 
 	"github.com/gorilla/mux"
 )
 
-func RegisterRoutes() *mux.Router {
+func RegisterRoutes(deps server.Dependencies) *mux.Router {
+	controller := controllers.NewController(deps)
 	r := mux.NewRouter()
-	r.HandleFunc("/register-did", controllers.RegisterDID).Methods("POST")
-	r.HandleFunc("/send-invitation", controllers.CreateInvitation).Methods("POST")
-	r.HandleFunc("/receive-invitation", controllers.ReceiveInvitation).Methods("POST")
-	r.HandleFunc("/connections", controllers.GetConnections).Methods("POST")
-	r.HandleFunc("/send-presentation-request", controllers.SendProofRequest).Methods("POST")
-	r.HandleFunc("/schemasGet", controllers.GetSchemasDB).Methods("GET")
-	r.HandleFunc("/recordsByUser", controllers.VerifyPresentation).Methods("POST")
-	// r.HandleFunc("/records",controllers.GetRecords).Methods("POST")
+	r.HandleFunc("/health", controller.Health).Methods("GET")
+	webhooks.RegisterRoutes(r, deps)
+
+	// The event stream takes its token from the query string as well
+	r.Handle("/events", deps.Tokens.StreamMiddleware(http.HandlerFunc(deps.Events.Stream))).Methods("GET")
+
+	// Everything else needs an access token
+	api := r.NewRoute().Subrouter()
+	api.Use(deps.Tokens.Middleware)
+	api.HandleFunc("/register-did", controller.RegisterDID).Methods("POST")
+	api.HandleFunc("/send-invitation", controller.CreateInvitation).Methods("POST")
+	api.HandleFunc("/receive-invitation", controller.ReceiveInvitation).Methods("POST")
+	api.HandleFunc("/connections", controller.GetConnections).Methods("GET", "POST")
+	api.HandleFunc("/send-presentation-request", controller.SendProofRequest).Methods("POST")
+	api.HandleFunc("/schemasGet", controller.GetSchemasDB).Methods("GET")
+	api.HandleFunc("/recordsByUser", controller.VerifyPresentation).Methods("POST")
+	connections.RegisterRoutes(api, deps)
+	invitations.RegisterRoutes(api, deps)
+	messages.RegisterRoutes(api, deps)
+	account.RegisterRoutes(r, api, deps.Store, deps.Tokens)
 	return r
 }
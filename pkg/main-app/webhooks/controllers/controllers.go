@@ -0,0 +1,378 @@
+package webhooks
+
+import (
+	"context"
+	"crypto/subtle"
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/main-app/auth"
+	connstate "digiauth/pkg/main-app/connections"
+	"digiauth/pkg/main-app/db"
+	sql "digiauth/pkg/main-app/db/sqlconfig"
+	"digiauth/pkg/main-app/events"
+	"digiauth/pkg/main-app/exchanges"
+	lifecycle "digiauth/pkg/main-app/invitations"
+	"digiauth/pkg/main-app/messages"
+	"digiauth/pkg/main-app/server"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Controller receives the webhooks of one role's agent and records the state
+// transitions they carry, pushing the ones frontends care about to the broker
+type Controller struct {
+	role   string
+	agent  acapy.Agent
+	store  *db.Store
+	apiKey string
+	broker *events.Broker
+	sealer *exchanges.Sealer
+}
+
+func NewController(deps server.Dependencies) *Controller {
+	return &Controller{
+		role:   deps.Role,
+		agent:  deps.Agent,
+		store:  deps.Store,
+		apiKey: deps.Config.Webhooks.APIKey,
+		broker: deps.Events,
+		sealer: deps.Sealer,
+	}
+}
+
+func (c *Controller) HandleTopic(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+	defer cancel()
+
+	// Anyone reaching the server could otherwise fake agent events
+	if c.apiKey == "" || subtle.ConstantTimeCompare([]byte(r.Header.Get(acapy.APIKeyHeader)), []byte(c.apiKey)) != 1 {
+		auth.WriteError(w, http.StatusUnauthorized, "unauthorized", "Invalid webhook API key")
+		return
+	}
+
+	topic := mux.Vars(r)["topic"]
+	var err error
+	var event events.Event
+	switch topic {
+	case acapy.TopicConnections:
+		var record acapy.ConnRecord
+		if !decode(w, r, &record) {
+			return
+		}
+		err = c.store.CreateConnectionEvent(ctx, sql.CreateConnectionEventParams{
+			Role:         c.role,
+			ConnectionID: record.ConnectionID,
+			State:        record.State,
+			Rfc23State:   record.RFC23State,
+			TheirLabel:   record.TheirLabel,
+			TheirDid:     record.TheirDID,
+		})
+		if record.State == "active" || record.State == "completed" {
+			event = events.Event{Type: events.ConnectionEstablished, ConnectionID: record.ConnectionID, State: record.State}
+		}
+		// A connection request means someone accepted the invitation, maybe
+		// from a wallet outside DigiAuth
+		switch record.State {
+		case "request", "response", "active", "completed":
+			var refused bool
+			if err == nil {
+				refused, err = c.refuseClosedInvitation(ctx, record)
+			}
+			if refused {
+				record.State = connstate.StateAbandoned
+				event = events.Event{}
+				break
+			}
+			if err == nil {
+				_, err = c.store.AcceptInvitation(ctx, record.ConnectionID)
+			}
+			if err == nil {
+				err = c.attribute(ctx, record)
+			}
+		}
+		if err == nil && record.State != "" {
+			_, err = c.store.UpdateConnectionState(ctx, sql.UpdateConnectionStateParams{
+				State:        record.State,
+				TheirLabel:   record.TheirLabel,
+				TheirDid:     record.TheirDID,
+				ConnectionID: record.ConnectionID,
+				Role:         c.role,
+			})
+		}
+	case acapy.TopicIssueCredentialV20:
+		var record acapy.CredentialExchange
+		if !decode(w, r, &record) {
+			return
+		}
+		err = c.store.CreateCredentialExchangeEvent(ctx, sql.CreateCredentialExchangeEventParams{
+			Role:         c.role,
+			CredExID:     record.CredExID,
+			ConnectionID: record.ConnectionID,
+			ThreadID:     record.ThreadID,
+			State:        record.State,
+		})
+		if err == nil {
+			err = c.trackCredentialExchange(ctx, record)
+		}
+		switch record.State {
+		case "offer-sent", "offer-received":
+			event = events.Event{Type: events.CredentialOffered, ConnectionID: record.ConnectionID, RecordID: record.CredExID, State: record.State}
+		case "done":
+			event = events.Event{Type: events.CredentialIssued, ConnectionID: record.ConnectionID, RecordID: record.CredExID, State: record.State}
+		}
+	case acapy.TopicIssueCredentialV20Indy:
+		var record acapy.CredentialExchangeIndy
+		if !decode(w, r, &record) {
+			return
+		}
+		// The registry entry is what revoking the credential later needs
+		if record.RevRegID != "" && record.CredRevID != "" {
+			err = c.store.SetCredentialRevocationEntry(ctx, sql.SetCredentialRevocationEntryParams{
+				RevRegID:  record.RevRegID,
+				CredRevID: record.CredRevID,
+				CredExID:  record.CredExID,
+			})
+		}
+		// The wallet id is what ties a held credential to its holder
+		if err == nil && record.CredIDStored != "" {
+			err = c.store.SetStoredCredentialID(ctx, sql.SetStoredCredentialIDParams{
+				CredID:   record.CredIDStored,
+				CredExID: record.CredExID,
+			})
+		}
+	case acapy.TopicPresentProofV20:
+		var record acapy.PresentationExchange
+		if !decode(w, r, &record) {
+			return
+		}
+		err = c.store.CreatePresentationExchangeEvent(ctx, sql.CreatePresentationExchangeEventParams{
+			Role:         c.role,
+			PresExID:     record.PresExID,
+			ConnectionID: record.ConnectionID,
+			ThreadID:     record.ThreadID,
+			State:        record.State,
+			Verified:     record.Verified,
+		})
+		switch {
+		case record.State == "request-sent" || record.State == "request-received":
+			event = events.Event{Type: events.ProofRequested, ConnectionID: record.ConnectionID, RecordID: record.PresExID, State: record.State}
+		case record.State == "done" && record.Verified == "true":
+			event = events.Event{Type: events.ProofVerified, ConnectionID: record.ConnectionID, RecordID: record.PresExID, State: record.State}
+		}
+	case acapy.TopicRevocationRegistry:
+		var record acapy.RevocationRegistry
+		if !decode(w, r, &record) {
+			return
+		}
+		err = c.store.CreateRevocationRegistryEvent(ctx, sql.CreateRevocationRegistryEventParams{
+			Role:      c.role,
+			RevRegID:  record.RevocRegID,
+			CredDefID: record.CredDefID,
+			State:     record.State,
+		})
+	case acapy.TopicPing:
+		var record acapy.Ping
+		if !decode(w, r, &record) {
+			return
+		}
+		// Pings either way show the other agent is reachable
+		if record.State == acapy.PingReceived || record.State == acapy.PingResponseReceived {
+			err = c.store.MarkConnectionSeen(ctx, record.ConnectionID)
+		}
+		if record.State == acapy.PingResponseReceived {
+			event = events.Event{Type: events.PingResponded, ConnectionID: record.ConnectionID, RecordID: record.ThreadID, State: record.State}
+		}
+	case acapy.TopicBasicMessages:
+		var record acapy.BasicMessage
+		if !decode(w, r, &record) {
+			return
+		}
+		var stored bool
+		stored, err = c.storeMessage(ctx, record)
+		if stored {
+			event = events.Event{Type: events.MessageReceived, ConnectionID: record.ConnectionID, RecordID: record.MessageID, State: record.State}
+		}
+	default:
+		// The agent posts many more topics than we track, acknowledge them so
+		// it does not retry
+		log.Printf("Ignoring %s webhook topic %q", c.role, topic)
+	}
+
+	if err != nil {
+		log.Printf("Error storing %s webhook %q : %v", c.role, topic, err)
+		http.Error(w, "Error storing webhook event : "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if event.Type != "" {
+		c.publish(ctx, event)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write([]byte(`{}`))
+}
+
+// refuseClosedInvitation deletes connections made from an invitation that
+// was cancelled or expired before the other party answered it. Wallets
+// outside DigiAuth reach the agent without going through Receive.
+func (c *Controller) refuseClosedInvitation(ctx context.Context, record acapy.ConnRecord) (bool, error) {
+	invitation, err := c.store.GetInvitation(ctx, record.ConnectionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	status := lifecycle.Status(invitation, time.Now())
+	if status != lifecycle.StatusExpired && status != lifecycle.StatusRevoked {
+		return false, nil
+	}
+
+	log.Printf("Refusing %s connection %s made from a %s invitation", c.role, record.ConnectionID, status)
+	if err := c.agent.DeleteConnection(ctx, record.ConnectionID); err != nil && !acapy.IsNotFound(err) {
+		return true, err
+	}
+	return true, nil
+}
+
+// attribute records a connection made from a public invitation as belonging
+// to the user who published it. The agent makes a new record for each wallet
+// accepting a multi-use invitation, sharing its message ID or recipient key.
+func (c *Controller) attribute(ctx context.Context, record acapy.ConnRecord) error {
+	if record.InvitationMsgID == "" && record.InvitationKey == "" {
+		return nil
+	}
+	invitation, err := c.store.FindPublicInvitation(ctx, sql.FindPublicInvitationParams{
+		Role:            c.role,
+		InvitationMsgID: record.InvitationMsgID,
+		InvitationKey:   record.InvitationKey,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if invitation.ConnectionID == record.ConnectionID {
+		return nil
+	}
+
+	owner, err := c.store.GetUserByID(ctx, invitation.UserID)
+	if err != nil {
+		return err
+	}
+	return c.store.CreateConnectionIfMissing(ctx, sql.CreateConnectionIfMissingParams{
+		ConnectionID: record.ConnectionID,
+		ID:           owner.ID,
+		MyMailID:     owner.Email,
+		Role:         c.role,
+	})
+}
+
+// trackCredentialExchange moves the local record of the exchange along.
+// Holders have none until the first offer arrives, and an issuer's webhook
+// can beat IssueCredential to recording the exchange it started. It is
+// created then for the user owning the connection.
+func (c *Controller) trackCredentialExchange(ctx context.Context, record acapy.CredentialExchange) error {
+	updated, err := c.store.UpdateCredentialExchangeState(ctx, sql.UpdateCredentialExchangeStateParams{
+		State:    record.State,
+		ThreadID: record.ThreadID,
+		CredExID: record.CredExID,
+	})
+	if err != nil || updated > 0 {
+		return err
+	}
+
+	connection, err := c.store.GetConnectionByID(ctx, record.ConnectionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var attributes []acapy.CredentialAttribute
+	if record.CredPreview != nil {
+		attributes = record.CredPreview.Attributes
+	}
+	sealed, encrypted, err := c.sealer.Seal(attributes)
+	if err != nil {
+		return err
+	}
+	params := sql.UpsertCredentialExchangeParams{
+		CredExID:            record.CredExID,
+		Role:                c.role,
+		UserID:              connection.ID,
+		ConnectionID:        record.ConnectionID,
+		CounterpartyEmail:   connection.TheirMailID,
+		ThreadID:            record.ThreadID,
+		Attributes:          sealed,
+		AttributesEncrypted: encrypted,
+		State:               record.State,
+	}
+	if record.ByFormat != nil {
+		params.SchemaID = record.ByFormat.CredOffer.Indy.SchemaID
+		params.CredDefID = record.ByFormat.CredOffer.Indy.CredDefID
+	}
+	return c.store.UpsertCredentialExchange(ctx, params)
+}
+
+// storeMessage records a basic message received over a known connection. It
+// reports false for unknown connections and repeated deliveries, which have
+// nobody new to notify.
+func (c *Controller) storeMessage(ctx context.Context, record acapy.BasicMessage) (bool, error) {
+	_, err := c.store.GetConnectionByID(ctx, record.ConnectionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("Ignoring %s message on unknown connection %s", c.role, record.ConnectionID)
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	sentAt := time.Now()
+	for _, layout := range []string{acapy.TimeLayout, time.RFC3339Nano} {
+		if t, err := time.Parse(layout, record.SentTime); err == nil {
+			sentAt = t
+			break
+		}
+	}
+	_, err = c.store.CreateBasicMessage(ctx, sql.CreateBasicMessageParams{
+		ConnectionID: record.ConnectionID,
+		Direction:    messages.DirectionReceived,
+		Content:      record.Content,
+		MessageID:    record.MessageID,
+		SentAt:       pgtype.Timestamptz{Time: sentAt, Valid: true},
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// publish sends the event to the user owning its connection. Connections the
+// server does not know about yet have nobody to notify.
+func (c *Controller) publish(ctx context.Context, event events.Event) {
+	connection, err := c.store.GetConnectionByID(ctx, event.ConnectionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		log.Printf("Error resolving owner of %s connection %s : %v", c.role, event.ConnectionID, err)
+		return
+	}
+	event.UserID = connection.ID
+	c.broker.Publish(event)
+}
+
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		http.Error(w, "Invalid webhook payload", http.StatusBadRequest)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package webhooks
+
+import (
+	"digiauth/pkg/acapy"
+	"digiauth/pkg/acapy/acapytest"
+	"digiauth/pkg/main-app/config"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestHandleTopicAPIKey(t *testing.T) {
+	const key = "0123456789abcdef-webhook"
+
+	tests := []struct {
+		name       string
+		configured string
+		header     string
+		sendHeader bool
+		topic      string
+		wantStatus int
+	}{
+		{name: "matching key", configured: key, header: key, sendHeader: true, topic: "endorse_transaction", wantStatus: http.StatusOK},
+		{name: "missing key", configured: key, topic: acapy.TopicConnections, wantStatus: http.StatusUnauthorized},
+		{name: "empty key", configured: key, header: "", sendHeader: true, topic: acapy.TopicConnections, wantStatus: http.StatusUnauthorized},
+		{name: "wrong key", configured: key, header: "not-the-webhook-key", sendHeader: true, topic: acapy.TopicConnections, wantStatus: http.StatusUnauthorized},
+		{name: "key with a suffix", configured: key, header: key + "x", sendHeader: true, topic: acapy.TopicConnections, wantStatus: http.StatusUnauthorized},
+		{name: "no key configured", configured: "", topic: acapy.TopicConnections, wantStatus: http.StatusUnauthorized},
+		{name: "no key configured, empty key sent", configured: "", header: "", sendHeader: true, topic: acapy.TopicConnections, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Rejected webhooks must not reach the store or the agent, both
+			// are left unset or fail every call
+			agent := &acapytest.Agent{}
+			c := &Controller{role: config.RoleIssuer, agent: agent, apiKey: tt.configured}
+
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/topic/"+tt.topic+"/", strings.NewReader(`{"connection_id": "conn-1", "state": "active"}`))
+			if tt.sendHeader {
+				req.Header.Set(acapy.APIKeyHeader, tt.header)
+			}
+			req = mux.SetURLVars(req, map[string]string{"topic": tt.topic})
+			rec := httptest.NewRecorder()
+
+			c.HandleTopic(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body.String())
+			}
+			if calls := agent.Calls(); len(calls) != 0 {
+				t.Errorf("agent was called: %v", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,17 @@
+package webhooks
+
+import (
+	"digiauth/pkg/main-app/server"
+	controllers "digiauth/pkg/main-app/webhooks/controllers"
+
+	"github.com/gorilla/mux"
+)
+
+// RegisterRoutes adds the endpoint the role's agent posts its webhooks to.
+// Start the agent with --webhook-url http://<server>/webhooks#<api key> so it
+// posts to /webhooks/topic/<topic>/ with the configured webhooks api_key.
+func RegisterRoutes(r *mux.Router, deps server.Dependencies) {
+	controller := controllers.NewController(deps)
+	r.HandleFunc("/webhooks/topic/{topic}", controller.HandleTopic).Methods("POST")
+	r.HandleFunc("/webhooks/topic/{topic}/", controller.HandleTopic).Methods("POST")
+}